@@ -0,0 +1,152 @@
+package lvm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ActivationMode selects how a logical volume is activated in a volume group
+// which is shared between hosts.
+type ActivationMode string
+
+const (
+	// ActivationDefault lets lvm decide how to activate the volume.
+	ActivationDefault = ActivationMode("")
+	// ActivationExclusive activates the volume on this host only.
+	ActivationExclusive = ActivationMode("e")
+	// ActivationShared allows the volume to be active on other hosts, too.
+	ActivationShared = ActivationMode("s")
+	// ActivationLocal activates or deactivates the volume on this host
+	// without affecting other hosts.
+	ActivationLocal = ActivationMode("l")
+)
+
+// ActivationOptions controls how ActivateLogicalVolumeWithOptions and
+// DeactivateLogicalVolumeWithOptions change a logical volume.
+type ActivationOptions struct {
+	// Mode is how the volume is activated.  For deactivation, only
+	// ActivationLocal has any effect.
+	Mode ActivationMode
+	// IgnoreActivationSkip activates the volume even if it is flagged to be
+	// skipped during activation.
+	IgnoreActivationSkip bool
+	// ReadOnly activates the volume read-only, without changing the
+	// permissions recorded in the volume group's metadata.
+	ReadOnly bool
+}
+
+func (o ActivationOptions) args(vgname, volume string, activate bool) []string {
+	value := "n"
+	if activate {
+		value = string(o.Mode) + "y"
+	} else if o.Mode == ActivationLocal {
+		value = "ln"
+	}
+	args := []string{"lvchange", "--activate", value}
+	if activate && o.IgnoreActivationSkip {
+		args = append(args, "--ignoreactivationskip")
+	}
+	if activate && o.ReadOnly {
+		args = append(args, "--config", "activation { read_only_volume_list = [ \""+vgname+"/"+volume+"\" ] }")
+	}
+	return append(args, vgname+"/"+volume)
+}
+
+// ActivateLogicalVolumeWithOptions is a wrapper around DefaultClient.ActivateLogicalVolumeWithOptions.
+func ActivateLogicalVolumeWithOptions(vgname, volume string, options ActivationOptions) error {
+	return DefaultClient.ActivateLogicalVolumeWithOptions(vgname, volume, options)
+}
+
+// ActivateLogicalVolumeWithOptions activates a single logical volume in the
+// specified volume group, making it visible.
+func (c *Client) ActivateLogicalVolumeWithOptions(vgname, volume string, options ActivationOptions) error {
+	args := options.args(vgname, volume, true)
+	_, err := c.mutate([]string{vgname}, args...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvchange --activate %s\" for %q", args[2], vgname+"/"+volume)
+	}
+	return nil
+}
+
+// DeactivateLogicalVolumeWithOptions is a wrapper around DefaultClient.DeactivateLogicalVolumeWithOptions.
+func DeactivateLogicalVolumeWithOptions(vgname, volume string, options ActivationOptions) error {
+	return DefaultClient.DeactivateLogicalVolumeWithOptions(vgname, volume, options)
+}
+
+// DeactivateLogicalVolumeWithOptions deactivates a single logical volume in
+// the specified volume group, making it invisible.
+func (c *Client) DeactivateLogicalVolumeWithOptions(vgname, volume string, options ActivationOptions) error {
+	args := options.args(vgname, volume, false)
+	_, err := c.mutate([]string{vgname}, args...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvchange --activate %s\" for %q", args[2], vgname+"/"+volume)
+	}
+	return nil
+}
+
+// RefreshLogicalVolume is a wrapper around DefaultClient.RefreshLogicalVolume.
+func RefreshLogicalVolume(vgname, volume string) error {
+	return DefaultClient.RefreshLogicalVolume(vgname, volume)
+}
+
+// RefreshLogicalVolume reloads the device-mapper tables of an active logical
+// volume from the volume group's metadata.
+func (c *Client) RefreshLogicalVolume(vgname, volume string) error {
+	_, err := c.mutate([]string{vgname}, "lvchange", "--refresh", vgname+"/"+volume)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvchange --refresh\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+// SetActivationSkip is a wrapper around DefaultClient.SetActivationSkip.
+func SetActivationSkip(vgname, volume string, skip bool) error {
+	return DefaultClient.SetActivationSkip(vgname, volume, skip)
+}
+
+// SetActivationSkip sets or clears the flag which causes a logical volume to
+// be skipped when its volume group is activated, or when it is activated
+// without ignoring the flag.
+func (c *Client) SetActivationSkip(vgname, volume string, skip bool) error {
+	_, err := c.mutate([]string{vgname}, "lvchange", "--setactivationskip", yesNo(skip), vgname+"/"+volume)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvchange --setactivationskip\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+// SetVolumeGroupAutoActivation is a wrapper around DefaultClient.SetVolumeGroupAutoActivation.
+func SetVolumeGroupAutoActivation(vgname string, enabled bool) error {
+	return DefaultClient.SetVolumeGroupAutoActivation(vgname, enabled)
+}
+
+// SetVolumeGroupAutoActivation controls whether the logical volumes in a
+// volume group are activated automatically when its devices appear.
+func (c *Client) SetVolumeGroupAutoActivation(vgname string, enabled bool) error {
+	_, err := c.mutate([]string{vgname}, "vgchange", "--setautoactivation", yesNo(enabled), vgname)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgchange --setautoactivation\" for %q", vgname)
+	}
+	return nil
+}
+
+// SetLogicalVolumeAutoActivation is a wrapper around DefaultClient.SetLogicalVolumeAutoActivation.
+func SetLogicalVolumeAutoActivation(vgname, volume string, enabled bool) error {
+	return DefaultClient.SetLogicalVolumeAutoActivation(vgname, volume, enabled)
+}
+
+// SetLogicalVolumeAutoActivation controls whether a logical volume is
+// activated automatically when its volume group's devices appear.
+func (c *Client) SetLogicalVolumeAutoActivation(vgname, volume string, enabled bool) error {
+	_, err := c.mutate([]string{vgname}, "lvchange", "--setautoactivation", yesNo(enabled), vgname+"/"+volume)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvchange --setautoactivation\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "y"
+	}
+	return "n"
+}
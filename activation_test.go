@@ -0,0 +1,26 @@
+package lvm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestActivationOptions(t *testing.T) {
+	tests := []struct {
+		options  ActivationOptions
+		activate bool
+		expected string
+	}{
+		{ActivationOptions{}, true, "lvchange --activate y vg/lv"},
+		{ActivationOptions{Mode: ActivationExclusive, IgnoreActivationSkip: true}, true, "lvchange --activate ey --ignoreactivationskip vg/lv"},
+		{ActivationOptions{Mode: ActivationShared, ReadOnly: true}, true, `lvchange --activate sy --config activation { read_only_volume_list = [ "vg/lv" ] } vg/lv`},
+		{ActivationOptions{Mode: ActivationExclusive, ReadOnly: true}, false, "lvchange --activate n vg/lv"},
+		{ActivationOptions{Mode: ActivationLocal}, false, "lvchange --activate ln vg/lv"},
+	}
+	for _, test := range tests {
+		args := strings.Join(test.options.args("vg", "lv", test.activate), " ")
+		if args != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, args)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package lvm
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuditEvent records a command which modified storage, or tried to.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Operation is the lvm subcommand, for example "lvcreate".
+	Operation string   `json:"operation"`
+	Args      []string `json:"args"`
+	// VGNames lists the volume groups which the command operated on.
+	VGNames []string `json:"vgs,omitempty"`
+	// LVName is the logical volume which the command operated on, if there
+	// was one.
+	LVName   string        `json:"lv,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// ExitCode is the command's exit status, or -1 if it couldn't be run.
+	ExitCode int    `json:"exit_code"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// DryRun is set if the command was only recorded by a dry run, so it
+	// didn't actually change anything.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// AuditSink receives AuditEvents.
+type AuditSink interface {
+	Record(event AuditEvent) error
+}
+
+// FileAuditSink writes AuditEvents to a file, one JSON object per line.
+type FileAuditSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileAuditSink opens the specified file for appending AuditEvents to it,
+// creating it if it doesn't already exist.
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening audit log %q", path)
+	}
+	return &FileAuditSink{f: f}, nil
+}
+
+// Record appends an event to the file.
+func (s *FileAuditSink) Record(event AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "error encoding audit event")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.f.Write(append(b, '\n')); err != nil {
+		return errors.Wrapf(err, "error writing to audit log %q", s.f.Name())
+	}
+	return nil
+}
+
+// Close closes the file.
+func (s *FileAuditSink) Close() error {
+	return s.f.Close()
+}
+
+// newAuditEvent describes a command which was run, or only recorded if dryRun
+// is set.
+func newAuditEvent(cmd Command, start time.Time, err error, dryRun bool) AuditEvent {
+	event := AuditEvent{
+		Time:      start,
+		Operation: cmd.Subcommand(),
+		Args:      cmd.Args,
+		VGNames:   cmd.VGNames,
+		LVName:    cmd.LVName(),
+		Duration:  time.Since(start),
+		ExitCode:  ExitCode(err),
+		DryRun:    dryRun,
+	}
+	if err != nil {
+		event.Error = err.Error()
+		if cmdErr, ok := errors.Cause(err).(*CommandError); ok {
+			event.Stderr = cmdErr.Stderr
+		}
+	}
+	return event
+}
@@ -0,0 +1,101 @@
+package lvm
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "lvm")
+	contents := "#!/bin/sh\nif test \"$1\" = lvremove; then echo \"  Logical volume vg/busy in use.\" >&2; exit 5; fi\necho '{\"report\": []}'\n"
+	if err = ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewFileAuditSink(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{LVMPath: script, Audit: sink}
+
+	if err = c.ActivateLogicalVolume("vg", "lv"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.RemoveLogicalVolume("vg", "busy"); err == nil {
+		t.Fatal("expected lvremove to fail")
+	}
+	if _, err = c.GetVolumeGroups("vg"); err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	events := []AuditEvent{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event := AuditEvent{}
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %+v", events)
+	}
+	if events[0].Operation != "lvchange" || events[0].LVName != "lv" || events[0].ExitCode != 0 || events[0].DryRun || len(events[0].VGNames) != 1 || events[0].VGNames[0] != "vg" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+	if events[1].Operation != "lvremove" || events[1].LVName != "busy" || events[1].ExitCode != 5 || events[1].Stderr == "" {
+		t.Errorf("unexpected event %+v", events[1])
+	}
+}
+
+// auditEvents is an AuditSink which keeps events in memory.
+type auditEvents []AuditEvent
+
+func (a *auditEvents) Record(event AuditEvent) error {
+	*a = append(*a, event)
+	return nil
+}
+
+func TestAuditDryRun(t *testing.T) {
+	events := &auditEvents{}
+	c := &Client{LVMPath: "lvm", Executor: &RecordingExecutor{}, Audit: events}
+	if err := c.RemoveLogicalVolume("vg", "lv"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*events) != 1 || (*events)[0].Operation != "lvremove" || !(*events)[0].DryRun {
+		t.Fatalf("expected one dry-run event, got %+v", *events)
+	}
+	b, err := json.Marshal((*events)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"dry_run":true`) {
+		t.Fatalf("expected the dry run to be marked in %s", b)
+	}
+
+	*events = nil
+	c = &Client{LVMPath: "lvm", Executor: &HookedExecutor{Executor: &RecordingExecutor{}}, Audit: events}
+	if err := c.RemoveLogicalVolume("vg", "lv"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*events) != 1 || !(*events)[0].DryRun {
+		t.Fatalf("expected a wrapped recorder to be treated as a dry run, got %+v", *events)
+	}
+}
@@ -0,0 +1,135 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/haircommander/lvm-go/metadata"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// MetadataArchiveDir is the directory in which lvm keeps the copies of
+	// volume group metadata that it archives before making changes.
+	MetadataArchiveDir = "/etc/lvm/archive"
+)
+
+// BackupVolumeGroupMetadata is a wrapper around DefaultClient.BackupVolumeGroupMetadata.
+func BackupVolumeGroupMetadata(vgname, file string) (MetadataArchive, error) {
+	return DefaultClient.BackupVolumeGroupMetadata(vgname, file)
+}
+
+// BackupVolumeGroupMetadata writes a copy of the current metadata for the
+// specified volume group to the specified file, and returns a description of
+// the result.  In a dry run, the command is only recorded, and the description
+// lists just the file and the volume group.
+func (c *Client) BackupVolumeGroupMetadata(vgname, file string) (MetadataArchive, error) {
+	// vgcfgbackup doesn't change the volume group, but it does write the
+	// file, so it's marked as mutating to keep dry runs from running it.
+	_, err := c.run(Command{Args: []string{"vgcfgbackup", "--file", file, vgname}, VGNames: []string{vgname}, Mutating: true})
+	if err != nil {
+		return MetadataArchive{}, errors.Wrapf(err, "error running \"lvm vgcfgbackup\" for %q", vgname)
+	}
+	if c.dryRun() {
+		return MetadataArchive{Path: file, VGName: vgname}, nil
+	}
+	return ReadMetadataArchive(file)
+}
+
+// ListMetadataArchives returns descriptions of the metadata archives that lvm
+// has kept for the specified volume group, or for all volume groups, in order
+// of increasing sequence number.  Files which can't be read or parsed are
+// skipped, with a warning, so that one damaged archive doesn't hide the rest.
+func ListMetadataArchives(vgname string) ([]MetadataArchive, error) {
+	names, err := filepath.Glob(filepath.Join(MetadataArchiveDir, "*.vg"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing metadata archives in %q", MetadataArchiveDir)
+	}
+	archives := []MetadataArchive{}
+	for _, name := range names {
+		archive, err := ReadMetadataArchive(name)
+		if err != nil {
+			logrus.Warnf("skipping metadata archive: %v", err)
+			continue
+		}
+		if vgname != "" && archive.VGName != vgname {
+			continue
+		}
+		archives = append(archives, archive)
+	}
+	sort.SliceStable(archives, func(i, j int) bool {
+		if archives[i].VGName != archives[j].VGName {
+			return archives[i].VGName < archives[j].VGName
+		}
+		return archives[i].SequenceNumber < archives[j].SequenceNumber
+	})
+	return archives, nil
+}
+
+// ReadMetadataArchive reads the header and volume group identification from a
+// metadata backup or archive file.
+func ReadMetadataArchive(file string) (MetadataArchive, error) {
+	vg, err := readMetadataFile(file)
+	if err != nil {
+		return MetadataArchive{}, err
+	}
+	archive := MetadataArchive{
+		Path:           file,
+		VGName:         vg.Name,
+		VGUUID:         vg.ID,
+		SequenceNumber: vg.SequenceNumber,
+		Description:    vg.Description,
+		CreationHost:   vg.CreationHost,
+	}
+	if vg.CreationTime != 0 {
+		archive.CreationTime = time.Unix(vg.CreationTime, 0)
+	}
+	return archive, nil
+}
+
+// readMetadataFile parses a metadata backup or archive file.
+func readMetadataFile(file string) (*metadata.VolumeGroup, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading metadata archive %q", file)
+	}
+	vg, err := metadata.ParseVolumeGroup(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing metadata archive %q", file)
+	}
+	return vg, nil
+}
+
+// RestoreVolumeGroupMetadata is a wrapper around DefaultClient.RestoreVolumeGroupMetadata.
+func RestoreVolumeGroupMetadata(vgname, file string, force bool) error {
+	return DefaultClient.RestoreVolumeGroupMetadata(vgname, file, force)
+}
+
+// RestoreVolumeGroupMetadata replaces the metadata for the specified volume
+// group with the contents of the specified backup or archive file.  Restoring
+// metadata for a volume group which contains thin pools requires force.  The
+// file is checked for consistency before lvm is asked to use it.
+func (c *Client) RestoreVolumeGroupMetadata(vgname, file string, force bool) error {
+	vg, err := readMetadataFile(file)
+	if err != nil {
+		return err
+	}
+	if vg.Name != vgname {
+		return errors.Errorf("metadata archive %q is for volume group %q, not %q", file, vg.Name, vgname)
+	}
+	if err = vg.Validate(); err != nil {
+		return errors.Wrapf(err, "error validating metadata archive %q", file)
+	}
+	args := []string{"vgcfgrestore", "--file", file}
+	if force {
+		args = append(args, "--force")
+	}
+	_, err = c.mutate([]string{vgname}, append(args, vgname)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgcfgrestore\" for %q from %q", vgname, file)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func testMetadataArchive(vgname string, seqno int) string {
+	return `contents = "Text Format Volume Group"
+version = 1
+
+description = "Created *before* executing 'lvcreate'"
+
+creation_host = "localhost"
+creation_time = 1499696534
+
+` + vgname + ` {
+	id = "Ju0Ma8-6dZm-qWmb-xyDr-TnXS-lTbE-Ubbg3K"
+	seqno = ` + strconv.Itoa(seqno) + `
+	format = "lvm2"
+	status = ["RESIZEABLE", "READ", "WRITE"]
+	flags = []
+	extent_size = 8192
+	max_lv = 0
+	max_pv = 0
+	metadata_copies = 0
+}
+`
+}
+
+func TestListMetadataArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(saved string) { MetadataArchiveDir = saved }(MetadataArchiveDir)
+	MetadataArchiveDir = dir
+
+	files := map[string]string{
+		"vg_00002-1.vg":    testMetadataArchive("vg", 2),
+		"vg_00001-2.vg":    testMetadataArchive("vg", 1),
+		"other_00001-3.vg": testMetadataArchive("other", 1),
+		"vg_00003-4.vg":    "vg {\n\tseqno = \n",
+	}
+	for name, contents := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives, err := ListMetadataArchives("vg")
+	if err != nil {
+		t.Fatalf("expected a damaged archive to be skipped, got %v", err)
+	}
+	if len(archives) != 2 || archives[0].SequenceNumber != 1 || archives[1].SequenceNumber != 2 {
+		t.Fatalf("unexpected archives: %+v", archives)
+	}
+	if archives[0].Path != filepath.Join(dir, "vg_00001-2.vg") || archives[0].CreationHost != "localhost" {
+		t.Fatalf("unexpected archive: %+v", archives[0])
+	}
+	if archives, err = ListMetadataArchives(""); err != nil || len(archives) != 3 || archives[0].VGName != "other" {
+		t.Fatalf("unexpected archives for all volume groups: %+v, %v", archives, err)
+	}
+}
+
+func TestBackupVolumeGroupMetadataDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "vg.backup")
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: "lvm", Executor: recorder}
+	archive, err := c.BackupVolumeGroupMetadata("vg", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive.Path != file || archive.VGName != "vg" {
+		t.Fatalf("unexpected archive: %+v", archive)
+	}
+	commands := recorder.Commands()
+	if len(commands) != 1 || commands[0].String() != "lvm vgcfgbackup --file "+file+" vg" {
+		t.Fatalf("expected vgcfgbackup to be recorded, got %v", commands)
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected %q to not be written in a dry run: %v", file, err)
+	}
+}
@@ -0,0 +1,167 @@
+package lvm
+
+import (
+	"encoding/xml"
+	"os/exec"
+
+	"github.com/haircommander/lvm-go/dm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ThinDeltaPath is the path to the "thin_delta" command.
+	ThinDeltaPath string
+)
+
+func init() {
+	if p, err := exec.LookPath("thin_delta"); err == nil {
+		ThinDeltaPath = p
+	}
+}
+
+// Kinds of changes reported by BlockDiff.
+const (
+	// BlockChanged is a range which is mapped in both volumes, but to
+	// different data.
+	BlockChanged = "different"
+	// BlockAdded is a range which is mapped only in the child volume.
+	BlockAdded = "right_only"
+	// BlockRemoved is a range which is mapped only in the parent volume.
+	BlockRemoved = "left_only"
+)
+
+// BlockRange is a range of a thin volume, in bytes, which differs between a
+// thin snapshot and its origin.
+type BlockRange struct {
+	Kind   string
+	Offset int64
+	Length int64
+}
+
+// thinDelta is the output of "thin_delta".
+type thinDelta struct {
+	DataBlockSize int64 `xml:"data_block_size,attr"`
+	Diff          struct {
+		Ranges []struct {
+			XMLName xml.Name
+			Begin   int64 `xml:"begin,attr"`
+			Length  int64 `xml:"length,attr"`
+		} `xml:",any"`
+	} `xml:"diff"`
+}
+
+// parseThinDelta converts the output of "thin_delta" into byte ranges, using
+// the pool's chunk size, in bytes, if it is known, and the block size in the
+// output, which is in sectors, if it isn't.
+func parseThinDelta(output []byte, chunkSize int64) ([]BlockRange, error) {
+	delta := thinDelta{}
+	if err := xml.Unmarshal(output, &delta); err != nil {
+		return nil, errors.Wrapf(err, "error parsing output of thin_delta")
+	}
+	if chunkSize <= 0 {
+		chunkSize = delta.DataBlockSize * 512
+	}
+	if chunkSize <= 0 {
+		return nil, errors.New("unable to determine thin pool chunk size")
+	}
+	ranges := []BlockRange{}
+	for _, r := range delta.Diff.Ranges {
+		switch r.XMLName.Local {
+		case BlockChanged, BlockAdded, BlockRemoved:
+			ranges = append(ranges, BlockRange{
+				Kind:   r.XMLName.Local,
+				Offset: r.Begin * chunkSize,
+				Length: r.Length * chunkSize,
+			})
+		}
+	}
+	return ranges, nil
+}
+
+// BlockDiff is a wrapper around DefaultClient.BlockDiff.
+func BlockDiff(vgname, parent, child string) ([]BlockRange, error) {
+	return DefaultClient.BlockDiff(vgname, parent, child)
+}
+
+// BlockDiff returns the ranges of the child thin volume which differ from the
+// parent thin volume.  The volumes must be in the same thin pool, which must
+// be active.  A snapshot of the pool's metadata is reserved while it is read,
+// so that the volumes can remain in use.  Reserving the snapshot modifies the
+// pool without running a command, so BlockDiff can't be used in a dry run.
+func (c *Client) BlockDiff(vgname, parent, child string) ([]BlockRange, error) {
+	if ThinDeltaPath == "" {
+		return nil, errors.New("thin_delta command not found")
+	}
+	if c.dryRun() {
+		return nil, errors.New("BlockDiff reserves a thin pool metadata snapshot, which can't be done in a dry run")
+	}
+	report, err := c.GetFullReport(vgname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading information about volume group %q", vgname)
+	}
+	volumes := map[string]ReportLVFull{}
+	segs := map[string]ReportSegFull{}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
+		}
+		for _, lv := range entry.LVs {
+			volumes[stripBrackets(lv.Name)] = lv
+		}
+		for _, seg := range entry.Segs {
+			if _, ok := segs[seg.LVUUID]; !ok {
+				segs[seg.LVUUID] = seg
+			}
+		}
+	}
+	ids := []string{}
+	pool := ""
+	for _, name := range []string{parent, child} {
+		lv, ok := volumes[name]
+		if !ok {
+			return nil, errors.Errorf("no LV named %q found", vgname+"/"+name)
+		}
+		seg := segs[lv.UUID]
+		if seg.Type != "thin" || seg.ThinID == "" {
+			return nil, errors.Errorf("LV %q is not a thin volume", vgname+"/"+name)
+		}
+		if pool != "" && stripBrackets(lv.PoolLV) != pool {
+			return nil, errors.Errorf("LVs %q and %q are not in the same thin pool", vgname+"/"+parent, vgname+"/"+child)
+		}
+		pool = stripBrackets(lv.PoolLV)
+		ids = append(ids, seg.ThinID)
+	}
+	chunkSize := segs[volumes[pool].UUID].ChunkSize
+
+	control, err := dm.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer control.Close()
+	info, _, err := findThinPoolDevice(control, vgname, pool)
+	if err != nil {
+		return nil, err
+	}
+	device := dm.Device{Name: info.Name}
+	if _, err = control.TargetMessage(device, 0, "reserve_metadata_snap"); err != nil {
+		return nil, errors.Wrapf(err, "error reserving metadata snapshot of thin pool %q", vgname+"/"+pool)
+	}
+	defer func() {
+		if _, err := control.TargetMessage(device, 0, "release_metadata_snap"); err != nil {
+			logrus.Errorf("error releasing metadata snapshot of thin pool %q: %v", vgname+"/"+pool, err)
+		}
+	}()
+
+	metadataDevice := "/dev/mapper/" + DeviceMapperName(vgname, pool+"_tmeta")
+	output, err := c.run(Command{Path: ThinDeltaPath, Args: []string{"--metadata-snap", "--snap1", ids[0], "--snap2", ids[1], metadataDevice}})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error running \"thin_delta\" for %q and %q", vgname+"/"+parent, vgname+"/"+child)
+	}
+	ranges, err := parseThinDelta([]byte(output), chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Debugf("%d ranges differ between %q and %q (thin IDs %s and %s)", len(ranges), parent, child, ids[0], ids[1])
+	return ranges, nil
+}
@@ -0,0 +1,41 @@
+package lvm
+
+import (
+	"testing"
+)
+
+func TestParseThinDelta(t *testing.T) {
+	output := []byte(`<superblock uuid="" time="2" transaction="4" data_block_size="128" nr_data_blocks="1600">
+  <diff left="1" right="2">
+    <same begin="0" length="16"/>
+    <different begin="16" length="4"/>
+    <right_only begin="20" length="2"/>
+    <left_only begin="30" length="1"/>
+  </diff>
+</superblock>`)
+	ranges, err := parseThinDelta(output, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BlockRange{
+		{Kind: BlockChanged, Offset: 16 * 65536, Length: 4 * 65536},
+		{Kind: BlockAdded, Offset: 20 * 65536, Length: 2 * 65536},
+		{Kind: BlockRemoved, Offset: 30 * 65536, Length: 65536},
+	}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Errorf("range %d: expected %+v, got %+v", i, expected[i], ranges[i])
+		}
+	}
+
+	ranges, err = parseThinDelta(output, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges[0].Offset != 16*1024 {
+		t.Errorf("expected the reported chunk size to be used, got offset %d", ranges[0].Offset)
+	}
+}
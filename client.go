@@ -0,0 +1,181 @@
+package lvm
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Client runs lvm commands.  A Client with a non-zero CacheTTL remembers the
+// output of reporting commands for that long, so that callers which read
+// reports frequently don't run lvm every time, and lets concurrent callers
+// which ask for the same report share a single run of lvm.  Any command which
+// modifies storage that is run through a Client discards everything that it
+// has cached.
+//
+// The zero value is a Client which does not cache.  The package-level
+// functions use DefaultClient.
+type Client struct {
+	// LVMPath is the path to the "lvm" command.  If it is not set, the
+	// package-level LVMPath is used.
+	LVMPath string
+	// CacheTTL is how long the results of reporting commands are reused.
+	CacheTTL time.Duration
+	// Namer converts IDs into logical volume names.  If it is not set,
+	// DefaultNamer is used.
+	Namer Namer
+	// Locks, if set, is used to serialize commands which modify a volume
+	// group.
+	Locks *LockManager
+	// Retry, if set, controls retrying of reporting commands and of commands
+	// which modify storage, including ones which fail because another
+	// process holds one of lvm's locks.
+	Retry *RetryPolicy
+	// Executor runs commands.  If it is not set, DefaultExecutor is used.
+	Executor Executor
+	// Audit, if set, receives an AuditEvent for every command which
+	// modifies storage.
+	Audit AuditSink
+
+	mu         sync.Mutex
+	generation uint64
+	cache      map[string]cachedReport
+	inflight   map[string]*reportCall
+}
+
+// DefaultClient is the Client used by the package-level functions.
+var DefaultClient = &Client{}
+
+// NewClient returns a Client which caches the results of reporting commands
+// for the specified length of time.
+func NewClient(cacheTTL time.Duration) *Client {
+	return &Client{CacheTTL: cacheTTL}
+}
+
+type cachedReport struct {
+	output  string
+	expires time.Time
+}
+
+type reportCall struct {
+	generation uint64
+	done       chan struct{}
+	output     string
+	err        error
+}
+
+func (c *Client) lvmPath() string {
+	if c.LVMPath != "" {
+		return c.LVMPath
+	}
+	return LVMPath
+}
+
+// Invalidate discards any cached reports.
+func (c *Client) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.cache = nil
+}
+
+// report runs an lvm command which produces a report and does not modify
+// anything, reusing a recent result if caching is enabled, or waiting for the
+// result of an identical command which is already running.
+func (c *Client) report(args ...string) (string, error) {
+	if c.CacheTTL <= 0 {
+		return c.run(Command{Args: args})
+	}
+	key := strings.Join(args, "\x00")
+	c.mu.Lock()
+	if cached, ok := c.cache[key]; ok && time.Now().Before(cached.expires) {
+		c.mu.Unlock()
+		return cached.output, nil
+	}
+	if call, ok := c.inflight[key]; ok && call.generation == c.generation {
+		c.mu.Unlock()
+		<-call.done
+		return call.output, call.err
+	}
+	call := &reportCall{generation: c.generation, done: make(chan struct{})}
+	if c.inflight == nil {
+		c.inflight = make(map[string]*reportCall)
+	}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.output, call.err = c.run(Command{Args: args})
+
+	c.mu.Lock()
+	if c.inflight[key] == call {
+		delete(c.inflight, key)
+	}
+	// Don't cache the result if something was modified while we were
+	// waiting for it.
+	if call.err == nil && call.generation == c.generation {
+		if c.cache == nil {
+			c.cache = make(map[string]cachedReport)
+		}
+		c.cache[key] = cachedReport{output: call.output, expires: time.Now().Add(c.CacheTTL)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.output, call.err
+}
+
+// query runs an lvm command which does not modify anything, but whose result
+// should not be cached, such as a scan for devices.
+func (c *Client) query(args ...string) (string, error) {
+	return c.run(Command{Args: args})
+}
+
+// mutate runs an lvm command which modifies storage in the specified volume
+// groups, holding the locks for them if the Client has a LockManager, and
+// then discards any cached reports.
+func (c *Client) mutate(vgnames []string, args ...string) (string, error) {
+	return c.mutateCommand(Command{Path: c.lvmPath(), Args: args, VGNames: vgnames, Mutating: true})
+}
+
+// mutateCommand runs a command which modifies storage in the volume groups
+// listed in cmd.VGNames, which need not be lvm, in the same way as mutate.
+func (c *Client) mutateCommand(cmd Command) (string, error) {
+	if c.Locks != nil {
+		unlock, err := c.Locks.Lock(cmd.VGNames...)
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+	}
+	// Deferred calls run in reverse order, so this discards cached reports
+	// before the locks are released, and nobody else can read stale ones.
+	defer c.Invalidate()
+	cmd.Mutating = true
+	start := time.Now()
+	output, err := c.run(cmd)
+	if c.Audit != nil {
+		if auditErr := c.Audit.Record(newAuditEvent(cmd, start, err, c.dryRun())); auditErr != nil {
+			logrus.Warnf("error recording %v in audit log: %v", cmd.Args, auditErr)
+		}
+	}
+	return output, err
+}
+
+// run runs a command, lvm unless cmd.Path is set, running it again if it fails
+// in a way that the Client's retry policy allows.
+func (c *Client) run(cmd Command) (string, error) {
+	if cmd.Path == "" {
+		cmd.Path = c.lvmPath()
+	}
+	policy := c.Retry
+	for attempt := 1; ; attempt++ {
+		output, err := c.executor().Run(cmd)
+		if err == nil || policy == nil || attempt >= policy.Backoff.Attempts || !policy.Retries(cmd, err) {
+			return output, err
+		}
+		delay := policy.Backoff.Delay(attempt)
+		logrus.Debugf("attempt %d of %d at running %v failed with a %s error, retrying in %v", attempt, policy.Backoff.Attempts, cmd.Args, Classify(err), delay)
+		time.Sleep(delay)
+	}
+}
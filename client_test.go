@@ -0,0 +1,71 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLVM writes a script which stands in for lvm, printing the specified
+// report and recording each set of arguments that it is run with.
+func fakeLVM(t *testing.T, output string) (string, func() []string) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "lvm")
+	if err = ioutil.WriteFile(filepath.Join(dir, "output"), []byte(output), 0644); err != nil {
+		t.Fatal(err)
+	}
+	contents := "#!/bin/sh\necho \"$*\" >> " + log + "\nsleep 0.1\ncat " + filepath.Join(dir, "output") + "\n"
+	if err = ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return script, func() []string {
+		b, err := ioutil.ReadFile(log)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	script, runs := fakeLVM(t, `{"report": []}`)
+	c := NewClient(time.Hour)
+	c.LVMPath = script
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetVolumeGroups("vg"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := c.GetVolumeGroups("vg"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(runs()); n != 1 {
+		t.Fatalf("expected concurrent and repeated reports to share 1 run of lvm, got %d", n)
+	}
+
+	if err := c.ActivateVolumeGroup("vg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetVolumeGroups("vg"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(runs()); n != 3 {
+		t.Fatalf("expected a mutating call to invalidate the cache, got %d runs", n)
+	}
+}
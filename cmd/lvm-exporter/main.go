@@ -0,0 +1,38 @@
+// lvm-exporter serves metrics about local storage for Prometheus to scrape.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/haircommander/lvm-go/collector"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	address := flag.String("listen", ":9845", "address to serve metrics on")
+	path := flag.String("path", "/metrics", "path to serve metrics at")
+	vgname := flag.String("vg", "", "volume group to report on (default: all)")
+	cacheTTL := flag.Duration("cache", 5*time.Second, "how long to reuse lvm reports")
+	debug := flag.Bool("debug", false, "log commands that are run")
+	flag.Parse()
+
+	if *debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+	client := lvm.NewClient(*cacheTTL)
+	commands := collector.NewCommandMetrics()
+	client.Executor = &lvm.HookedExecutor{Hooks: []lvm.CommandHook{commands}}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector.New(client, *vgname), commands)
+	http.Handle(*path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	logrus.Infof("serving metrics at %s%s", *address, *path)
+	if err := http.ListenAndServe(*address, nil); err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -0,0 +1,334 @@
+// lvm-go runs the same operations on local storage that the library performs
+// for its callers, so that they can be tried out and inspected by hand.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/haircommander/lvm-go/format"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Exit statuses.  Failures are reported using the class of the error that
+// caused them, so that scripts can tell them apart.
+const (
+	exitUsage = 2
+)
+
+var exitCodes = map[lvm.ErrorClass]int{
+	lvm.ErrorUnknown:         1,
+	lvm.ErrorNotFound:        3,
+	lvm.ErrorLockContention:  4,
+	lvm.ErrorDeviceBusy:      5,
+	lvm.ErrorUdev:            6,
+	lvm.ErrorNoSpace:         7,
+	lvm.ErrorPermission:      8,
+	lvm.ErrorInvalidArgument: 9,
+}
+
+// usageError is returned by a subcommand when it was run incorrectly.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitCode returns the exit status to use for an error.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if _, ok := errors.Cause(err).(usageError); ok {
+		return exitUsage
+	}
+	if code, ok := exitCodes[lvm.Classify(err)]; ok {
+		return code
+	}
+	return 1
+}
+
+type subcommand struct {
+	usage string
+	run   func(client *lvm.Client, args []string) error
+}
+
+var subcommands = map[string]subcommand{
+	"report":   {"report [-format json|table|csv] [-o COLUMNS] [-units b|iec|si] [-kind pv|vg|lv|seg] pvs|vgs|lvs|full [name]", report},
+	"path":     {"path -vg VG ID...", volumePath},
+	"pool":     {"pool info [-o FILE] VG POOL | pool verify FILE", pool},
+	"activate": {"activate [-mode e|s|l] [-ignore-skip] [-read-only] [-wait DURATION] [-deactivate] VG LV", activate},
+	"loopback": {"loopback list | loopback bootstrap -size SIZE [-pool POOL] FILE VG | loopback detach DEVICE", loopback},
+}
+
+var subcommandOrder = []string{"report", "path", "pool", "activate", "loopback"}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] command [arguments]\n\nCommands:\n", os.Args[0])
+	for _, name := range subcommandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", subcommands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	cacheTTL := flag.Duration("cache", 0, "how long to reuse lvm reports")
+	debug := flag.Bool("debug", false, "log commands that are run")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	cmd, ok := subcommands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(exitUsage)
+	}
+	err := cmd.run(lvm.NewClient(*cacheTTL), flag.Args()[1:])
+	if err != nil {
+		if _, ok := errors.Cause(err).(usageError); ok {
+			fmt.Fprintf(os.Stderr, "%v\nUsage: %s %s\n", err, os.Args[0], cmd.usage)
+		} else {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+	os.Exit(exitCode(err))
+}
+
+// parseFlags parses a subcommand's flags, and checks that it was given an
+// acceptable number of arguments.
+func parseFlags(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	flags.SetOutput(ioutil.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, usageError(err.Error())
+	}
+	if flags.NArg() < min || (max >= 0 && flags.NArg() > max) {
+		return nil, usageError("wrong number of arguments")
+	}
+	return flags.Args(), nil
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func report(client *lvm.Client, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	outputFormat := flags.String("format", "table", "output format: json, table, or csv")
+	columns := flags.String("o", "", "comma-separated list of columns to show in tables")
+	units := flags.String("units", "iec", "how to show sizes in tables: b, iec, or si")
+	kind := flags.String("kind", "lv", "objects to list in tables of full reports: pv, vg, lv, or seg")
+	args, err := parseFlags(flags, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	options := format.Options{}
+	if *columns != "" {
+		options.Columns = strings.Split(*columns, ",")
+	}
+	if options.Units, err = format.ParseUnits(*units); err != nil {
+		return usageError(err.Error())
+	}
+	if *outputFormat != "json" && *outputFormat != "table" && *outputFormat != "csv" {
+		return usageError(fmt.Sprintf("unknown format %q", *outputFormat))
+	}
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+	var data interface{}
+	tableKind := format.Kind(*kind)
+	switch args[0] {
+	case "pvs":
+		data, err = client.GetPhysicalVolumes(name)
+		tableKind = format.PVs
+	case "vgs":
+		data, err = client.GetVolumeGroups(name)
+		tableKind = format.VGs
+	case "lvs":
+		data, err = client.GetLogicalVolumes(name, "")
+		tableKind = format.LVs
+	case "full":
+		data, err = client.GetFullReport(name)
+	default:
+		return usageError(fmt.Sprintf("unknown report %q", args[0]))
+	}
+	if err != nil {
+		return err
+	}
+	if *outputFormat == "json" {
+		return printJSON(data)
+	}
+	return printReport(os.Stdout, data, tableKind, *outputFormat == "csv", options)
+}
+
+func volumePath(client *lvm.Client, args []string) error {
+	flags := flag.NewFlagSet("path", flag.ContinueOnError)
+	vgname := flags.String("vg", "", "volume group which holds the layers")
+	ids, err := parseFlags(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if *vgname == "" {
+		return usageError("no volume group specified")
+	}
+	results, err := client.VolumePathsForIDs(*vgname, ids)
+	if err != nil {
+		return err
+	}
+	// Report the first failure, but print everything that was found.
+	var failure error
+	for _, id := range ids {
+		result := results[id]
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", id, result.Err)
+			if failure == nil {
+				failure = result.Err
+			}
+			continue
+		}
+		fmt.Printf("%s\t%s\n", id, result.Path)
+	}
+	return failure
+}
+
+func pool(client *lvm.Client, args []string) error {
+	if len(args) < 1 {
+		return usageError("no pool command specified")
+	}
+	switch args[0] {
+	case "info":
+		flags := flag.NewFlagSet("pool info", flag.ContinueOnError)
+		output := flags.String("o", "", "also save the information to a file, for use with \"pool verify\"")
+		args, err := parseFlags(flags, args[1:], 2, 2)
+		if err != nil {
+			return err
+		}
+		history, err := client.ReadPoolInfo(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if *output != "" {
+			b, err := json.Marshal(history)
+			if err != nil {
+				return errors.Wrapf(err, "error encoding information about pool %q", args[0]+"/"+args[1])
+			}
+			if err = ioutil.WriteFile(*output, b, 0644); err != nil {
+				return errors.Wrapf(err, "error saving information about pool %q", args[0]+"/"+args[1])
+			}
+		}
+		return printJSON(history)
+	case "verify":
+		if len(args) != 2 {
+			return usageError("wrong number of arguments")
+		}
+		b, err := ioutil.ReadFile(args[1])
+		if err != nil {
+			return errors.Wrapf(err, "error reading pool history")
+		}
+		var history lvm.LvmPoolHistory
+		if err = json.Unmarshal(b, &history); err != nil {
+			return errors.Wrapf(err, "error decoding pool history from %q", args[1])
+		}
+		store := &lvm.LayerStore{Client: client, VGName: history.VGname, PoolName: history.PoolName, History: history}
+		if err = store.VerifyPool(); err != nil {
+			return err
+		}
+		fmt.Printf("thin pool %s/%s matches %s\n", history.VGname, history.PoolName, args[1])
+		return nil
+	}
+	return usageError(fmt.Sprintf("unknown pool command %q", args[0]))
+}
+
+func activate(client *lvm.Client, args []string) error {
+	flags := flag.NewFlagSet("activate", flag.ContinueOnError)
+	mode := flags.String("mode", "", "activation mode: e (exclusive), s (shared), or l (local)")
+	ignoreSkip := flags.Bool("ignore-skip", false, "activate the volume even if it is flagged to be skipped")
+	readOnly := flags.Bool("read-only", false, "activate the volume read-only")
+	wait := flags.Duration("wait", 0, "wait this long for the device to be ready, and print its path")
+	deactivate := flags.Bool("deactivate", false, "deactivate the volume instead")
+	args, err := parseFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	options := lvm.ActivationOptions{
+		Mode:                 lvm.ActivationMode(*mode),
+		IgnoreActivationSkip: *ignoreSkip,
+		ReadOnly:             *readOnly,
+	}
+	switch options.Mode {
+	case lvm.ActivationDefault, lvm.ActivationExclusive, lvm.ActivationShared, lvm.ActivationLocal:
+	default:
+		return usageError(fmt.Sprintf("unknown activation mode %q", *mode))
+	}
+	if *deactivate {
+		return client.DeactivateLogicalVolumeWithOptions(args[0], args[1], options)
+	}
+	if *wait > 0 {
+		path, err := client.ActivateLogicalVolumeAndWait(args[0], args[1], options, *wait)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	}
+	return client.ActivateLogicalVolumeWithOptions(args[0], args[1], options)
+}
+
+func loopback(client *lvm.Client, args []string) error {
+	if len(args) < 1 {
+		return usageError("no loopback command specified")
+	}
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return usageError("wrong number of arguments")
+		}
+		devices, err := client.GetLoopbackDevices()
+		if err != nil {
+			return err
+		}
+		for _, device := range devices.Loopback {
+			fmt.Printf("%s\t%s\n", device.Name, device.File)
+		}
+		return nil
+	case "bootstrap":
+		flags := flag.NewFlagSet("loopback bootstrap", flag.ContinueOnError)
+		size := flags.String("size", "", "size of the file to create, for example \"10G\"")
+		poolname := flags.String("pool", "", "also create a thin pool with this name")
+		args, err := parseFlags(flags, args[1:], 2, 2)
+		if err != nil {
+			return err
+		}
+		fileSize, err := lvm.ParseSize(*size)
+		if err != nil || fileSize.IsPercentage() || fileSize.Bytes <= 0 {
+			return usageError(fmt.Sprintf("invalid size %q", *size))
+		}
+		device, err := client.BootstrapLoopback(args[0], fileSize.Bytes, args[1], *poolname)
+		if device != "" {
+			fmt.Println(device)
+		}
+		return err
+	case "detach":
+		if len(args) != 2 {
+			return usageError("wrong number of arguments")
+		}
+		return client.DetachLoopback(args[1])
+	}
+	return usageError(fmt.Sprintf("unknown loopback command %q", args[0]))
+}
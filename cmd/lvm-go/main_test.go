@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/haircommander/lvm-go/format"
+	"github.com/pkg/errors"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{errors.New("something else"), 1},
+		{usageError("bad flag"), exitUsage},
+		{errors.Wrap(&lvm.CommandError{Stderr: "  Volume group \"vg\" not found"}, "error"), 3},
+		{&lvm.CommandError{Stderr: "  Can't get lock for vg"}, 4},
+		{&lvm.CommandError{ExitCode: 3}, 9},
+	} {
+		if code := exitCode(test.err); code != test.code {
+			t.Errorf("%v: expected exit code %d, got %d", test.err, test.code, code)
+		}
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	report := lvm.Report{Reports: []lvm.ReportEntry{{VGs: []lvm.ReportVG{{ReportVGCommon: lvm.ReportVGCommon{Name: "vg", PVCount: 1, Attributes: "wz--n-", Size: 1 << 30, Free: 512}}}}}}
+	var buf bytes.Buffer
+	if err := printReport(&buf, report, format.VGs, false, format.Options{Units: format.UnitsIEC}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "vg_name") || strings.Join(strings.Fields(lines[1]), " ") != "vg 1 0 0 wz--n- 1GiB 512B" {
+		t.Fatalf("unexpected table %q", buf.String())
+	}
+	buf.Reset()
+	if err := printReport(&buf, report, format.VGs, true, format.Options{Columns: []string{"vg_name", "vg_free"}}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "vg_name,vg_free\nvg,512\n" {
+		t.Fatalf("unexpected CSV %q", buf.String())
+	}
+	if err := printReport(&buf, report, format.VGs, false, format.Options{Columns: []string{"nonsense"}}); exitCode(err) != exitUsage {
+		t.Fatalf("expected a usage error for an unknown column, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"io"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/haircommander/lvm-go/format"
+	"github.com/pkg/errors"
+)
+
+// printReport writes a report as a table or as CSV.
+func printReport(w io.Writer, data interface{}, kind format.Kind, csv bool, options format.Options) error {
+	var table *format.Table
+	var err error
+	switch report := data.(type) {
+	case lvm.Report:
+		table, err = format.ReportTable(report, kind, options)
+	case lvm.ReportFull:
+		table, err = format.FullReportTable(report, kind, options)
+	default:
+		return errors.Errorf("unable to format report of type %T", data)
+	}
+	if err != nil {
+		return usageError(err.Error())
+	}
+	if csv {
+		return table.WriteCSV(w)
+	}
+	return table.WriteTable(w)
+}
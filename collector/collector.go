@@ -0,0 +1,173 @@
+// Package collector exports information about local storage which is read
+// using "lvm fullreport" as Prometheus metrics.
+package collector
+
+import (
+	"strconv"
+	"strings"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "lvm"
+
+var (
+	upDesc = prometheus.NewDesc(namespace+"_up", "Whether lvm could be queried successfully.", nil, nil)
+
+	vgSizeDesc    = prometheus.NewDesc(namespace+"_vg_size_bytes", "Size of the volume group.", []string{"vg"}, nil)
+	vgFreeDesc    = prometheus.NewDesc(namespace+"_vg_free_bytes", "Unallocated space in the volume group.", []string{"vg"}, nil)
+	vgMissingDesc = prometheus.NewDesc(namespace+"_vg_missing_pvs", "Number of physical volumes missing from the volume group.", []string{"vg"}, nil)
+
+	pvSizeDesc    = prometheus.NewDesc(namespace+"_pv_size_bytes", "Size of the physical volume.", []string{"vg", "pv"}, nil)
+	pvFreeDesc    = prometheus.NewDesc(namespace+"_pv_free_bytes", "Unallocated space on the physical volume.", []string{"vg", "pv"}, nil)
+	pvMissingDesc = prometheus.NewDesc(namespace+"_pv_missing", "Whether the physical volume is missing.", []string{"vg", "pv"}, nil)
+
+	lvSizeDesc   = prometheus.NewDesc(namespace+"_lv_size_bytes", "Size of the logical volume.", []string{"vg", "lv", "pool"}, nil)
+	lvActiveDesc = prometheus.NewDesc(namespace+"_lv_active", "Whether the logical volume is active.", []string{"vg", "lv", "pool"}, nil)
+
+	poolDataDesc     = prometheus.NewDesc(namespace+"_thin_pool_data_percent", "Percentage of the thin pool's data space which is in use.", []string{"vg", "pool"}, nil)
+	poolMetadataDesc = prometheus.NewDesc(namespace+"_thin_pool_metadata_percent", "Percentage of the thin pool's metadata space which is in use.", []string{"vg", "pool"}, nil)
+
+	snapshotDesc = prometheus.NewDesc(namespace+"_snapshot_percent", "Percentage of the snapshot's space which is in use.", []string{"vg", "lv", "origin"}, nil)
+
+	cacheReadHitsDesc    = prometheus.NewDesc(namespace+"_cache_read_hits_total", "Reads which were served from the cache.", []string{"vg", "lv"}, nil)
+	cacheReadMissesDesc  = prometheus.NewDesc(namespace+"_cache_read_misses_total", "Reads which were not served from the cache.", []string{"vg", "lv"}, nil)
+	cacheWriteHitsDesc   = prometheus.NewDesc(namespace+"_cache_write_hits_total", "Writes to blocks which were in the cache.", []string{"vg", "lv"}, nil)
+	cacheWriteMissesDesc = prometheus.NewDesc(namespace+"_cache_write_misses_total", "Writes to blocks which were not in the cache.", []string{"vg", "lv"}, nil)
+
+	raidSyncDesc = prometheus.NewDesc(namespace+"_raid_sync_percent", "Percentage of the RAID volume which is in sync.", []string{"vg", "lv"}, nil)
+)
+
+// Collector is a prometheus.Collector which reports on volume groups and the
+// physical and logical volumes in them.
+type Collector struct {
+	// Client is used to run lvm.  If it is not set, lvm.DefaultClient is
+	// used.
+	Client *lvm.Client
+	// VGName limits the collector to one volume group.  If it is empty, all
+	// volume groups are reported on.
+	VGName string
+}
+
+// New returns a Collector which reports on the specified volume group, or on
+// all volume groups if vgname is empty.
+func New(client *lvm.Client, vgname string) *Collector {
+	return &Collector{Client: client, VGName: vgname}
+}
+
+// Describe sends the descriptions of the metrics which the collector reports.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc,
+		vgSizeDesc, vgFreeDesc, vgMissingDesc,
+		pvSizeDesc, pvFreeDesc, pvMissingDesc,
+		lvSizeDesc, lvActiveDesc,
+		poolDataDesc, poolMetadataDesc,
+		snapshotDesc,
+		cacheReadHitsDesc, cacheReadMissesDesc, cacheWriteHitsDesc, cacheWriteMissesDesc,
+		raidSyncDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect reads a report and sends the metrics which are derived from it.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	client := c.Client
+	if client == nil {
+		client = lvm.DefaultClient
+	}
+	report, err := client.GetFullReport(c.VGName)
+	if err != nil {
+		logrus.Errorf("error reading lvm report: %v", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+	for _, entry := range report.Reports {
+		collectEntry(ch, entry)
+	}
+}
+
+func gauge(ch chan<- prometheus.Metric, desc *prometheus.Desc, value float64, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// percentage parses a percentage from a report, which is empty for volumes
+// which don't have that property.
+func percentage(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil
+}
+
+// counter sends a counter metric if a report has a value for it.
+func counter(ch chan<- prometheus.Metric, desc *prometheus.Desc, value string, labels ...string) {
+	if value == "" {
+		return
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, f, labels...)
+	}
+}
+
+func collectEntry(ch chan<- prometheus.Metric, entry lvm.ReportEntryFull) {
+	vgname := ""
+	for _, vg := range entry.VGs {
+		vgname = vg.Name
+		gauge(ch, vgSizeDesc, float64(vg.Size), vg.Name)
+		gauge(ch, vgFreeDesc, float64(vg.Free), vg.Name)
+		gauge(ch, vgMissingDesc, float64(vg.MissingPVCount), vg.Name)
+	}
+	for _, pv := range entry.PVs {
+		gauge(ch, pvSizeDesc, float64(pv.Size), vgname, pv.Name)
+		gauge(ch, pvFreeDesc, float64(pv.Free), vgname, pv.Name)
+		gauge(ch, pvMissingDesc, boolValue(pv.Missing != ""), vgname, pv.Name)
+	}
+	for _, lv := range entry.LVs {
+		// Internal volumes, like the data volumes of thin pools, are
+		// counted as part of the volumes which use them.
+		if strings.HasPrefix(lv.Name, "[") {
+			continue
+		}
+		pool := lv.PoolLV
+		gauge(ch, lvSizeDesc, float64(lv.Size), vgname, lv.Name, pool)
+		gauge(ch, lvActiveDesc, boolValue(lv.IsActive()), vgname, lv.Name, pool)
+		if len(lv.Attributes) == 0 {
+			continue
+		}
+		switch lv.Attributes[0] {
+		case 't':
+			if p, ok := percentage(lv.DataPercent); ok {
+				gauge(ch, poolDataDesc, p, vgname, lv.Name)
+			}
+			if p, ok := percentage(lv.MetadataPercent); ok {
+				gauge(ch, poolMetadataDesc, p, vgname, lv.Name)
+			}
+		case 's', 'S':
+			if p, ok := percentage(lv.SnapPercent); ok {
+				gauge(ch, snapshotDesc, p, vgname, lv.Name, lv.Origin)
+			}
+		case 'r', 'R':
+			if p, ok := percentage(lv.SyncPercent); ok {
+				gauge(ch, raidSyncDesc, p, vgname, lv.Name)
+			}
+		case 'C':
+			counter(ch, cacheReadHitsDesc, lv.CacheReadHits, vgname, lv.Name)
+			counter(ch, cacheReadMissesDesc, lv.CacheReadMisses, vgname, lv.Name)
+			counter(ch, cacheWriteHitsDesc, lv.CacheWriteHits, vgname, lv.Name)
+			counter(ch, cacheWriteMissesDesc, lv.CacheWriteMisses, vgname, lv.Name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollectEntry(t *testing.T) {
+	entry := lvm.ReportEntryFull{}
+	data := []byte(`{
+		"vg": [{"vg_name": "vg", "vg_size": "1073741824", "vg_free": "536870912", "vg_missing_pv_count": "0"}],
+		"pv": [{"pv_name": "/dev/sdb", "pv_size": "1073741824", "pv_free": "536870912", "pv_missing": ""}],
+		"lv": [
+			{"lv_name": "pool", "lv_attr": "twi-aotz--", "lv_size": "268435456", "data_percent": "12.50", "metadata_percent": "3.25"},
+			{"lv_name": "thin", "lv_attr": "Vwi-a-tz--", "lv_size": "1073741824", "pool_lv": "pool"},
+			{"lv_name": "mirror", "lv_attr": "rwi-a-r---", "lv_size": "1048576", "sync_percent": "100.00"},
+			{"lv_name": "[pool_tdata]", "lv_attr": "Twi-ao----", "lv_size": "268435456"},
+			{"lv_name": "[lvol0_pmspare]", "lv_attr": "ewi-------", "lv_size": "4194304"}
+		]
+	}`)
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	c := &testCollector{entry: entry}
+	expected := `
+# HELP lvm_thin_pool_data_percent Percentage of the thin pool's data space which is in use.
+# TYPE lvm_thin_pool_data_percent gauge
+lvm_thin_pool_data_percent{pool="pool",vg="vg"} 12.5
+# HELP lvm_raid_sync_percent Percentage of the RAID volume which is in sync.
+# TYPE lvm_raid_sync_percent gauge
+lvm_raid_sync_percent{lv="mirror",vg="vg"} 100
+# HELP lvm_lv_size_bytes Size of the logical volume.
+# TYPE lvm_lv_size_bytes gauge
+lvm_lv_size_bytes{lv="mirror",pool="",vg="vg"} 1.048576e+06
+lvm_lv_size_bytes{lv="pool",pool="",vg="vg"} 2.68435456e+08
+lvm_lv_size_bytes{lv="thin",pool="pool",vg="vg"} 1.073741824e+09
+# HELP lvm_vg_free_bytes Unallocated space in the volume group.
+# TYPE lvm_vg_free_bytes gauge
+lvm_vg_free_bytes{vg="vg"} 5.36870912e+08
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "lvm_thin_pool_data_percent", "lvm_raid_sync_percent", "lvm_lv_size_bytes", "lvm_vg_free_bytes"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testCollector reports on a fixed report entry.
+type testCollector struct {
+	entry lvm.ReportEntryFull
+}
+
+func (c *testCollector) Describe(ch chan<- *prometheus.Desc) {
+	(&Collector{}).Describe(ch)
+}
+
+func (c *testCollector) Collect(ch chan<- prometheus.Metric) {
+	collectEntry(ch, c.entry)
+}
+
+func TestCommandMetrics(t *testing.T) {
+	m := NewCommandMetrics()
+	m.CommandStarted(lvm.Command{Path: "/sbin/lvm", Args: []string{"vgs"}})(nil)
+	m.CommandStarted(lvm.Command{Path: "/sbin/lvm", Args: []string{"lvremove", "vg/lv"}})(&lvm.CommandError{Stderr: "Logical volume vg/lv in use.", ExitCode: 5})
+
+	if n := testutil.CollectAndCount(m, "lvm_command_duration_seconds"); n != 2 {
+		t.Fatalf("expected durations for 2 subcommands, got %d", n)
+	}
+	expected := `
+# HELP lvm_command_failures_total Commands which failed.
+# TYPE lvm_command_failures_total counter
+lvm_command_failures_total{class="device-busy",exit_code="5",subcommand="lvremove"} 1
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "lvm_command_failures_total"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"strconv"
+	"time"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CommandMetrics is an lvm.CommandHook which records how long commands take
+// and how often they fail.  It is also a prometheus.Collector which reports
+// those metrics.
+type CommandMetrics struct {
+	duration *prometheus.HistogramVec
+	failures *prometheus.CounterVec
+}
+
+// NewCommandMetrics returns a new CommandMetrics.
+func NewCommandMetrics() *CommandMetrics {
+	return &CommandMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "command_duration_seconds",
+			Help:      "Time taken to run commands.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"subcommand"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "command_failures_total",
+			Help:      "Commands which failed.",
+		}, []string{"subcommand", "class", "exit_code"}),
+	}
+}
+
+// CommandStarted starts timing a command.
+func (m *CommandMetrics) CommandStarted(cmd lvm.Command) func(err error) {
+	start := time.Now()
+	subcommand := cmd.Subcommand()
+	return func(err error) {
+		m.duration.WithLabelValues(subcommand).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.failures.WithLabelValues(subcommand, string(lvm.Classify(err)), strconv.Itoa(lvm.ExitCode(err))).Inc()
+		}
+	}
+}
+
+// Describe sends the descriptions of the command metrics.
+func (m *CommandMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.failures.Describe(ch)
+}
+
+// Collect sends the command metrics.
+func (m *CommandMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.failures.Collect(ch)
+}
@@ -0,0 +1,115 @@
+package lvm
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/haircommander/lvm-go/dm"
+	"github.com/pkg/errors"
+)
+
+// DeviceMapperName returns the name which lvm gives to the device-mapper
+// device for a logical volume: the volume group and logical volume names, with
+// any hyphens in them doubled, joined by a hyphen.
+func DeviceMapperName(vgname, volume string) string {
+	return strings.Replace(vgname, "-", "--", -1) + "-" + strings.Replace(volume, "-", "--", -1)
+}
+
+// deviceForLV identifies the device-mapper device of an active logical volume,
+// preferring the kernel's device number and falling back to its name.
+func deviceForLV(lv ReportLVFull) (dm.Device, error) {
+	if lv.KernelMajor > 0 && lv.KernelMinor >= 0 {
+		return dm.Device{Major: uint32(lv.KernelMajor), Minor: uint32(lv.KernelMinor)}, nil
+	}
+	if lv.DMPath != "" {
+		return dm.Device{Name: filepath.Base(lv.DMPath)}, nil
+	}
+	return dm.Device{}, errors.Errorf("LV %q is not active", lv.FullName)
+}
+
+// ReadLogicalVolumeStatus reads the device-mapper status of each of the
+// targets of an active logical volume, without running lvm.
+func ReadLogicalVolumeStatus(lv ReportLVFull) ([]dm.Target, error) {
+	device, err := deviceForLV(lv)
+	if err != nil {
+		return nil, err
+	}
+	control, err := dm.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer control.Close()
+	targets, err := control.TableStatus(device)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading status of LV %q", lv.FullName)
+	}
+	return targets, nil
+}
+
+// readTargetStatus returns the status of the first target of the specified
+// type in the logical volume's device, or in the device with the specified
+// layer suffix which lvm stacks underneath it.
+func readTargetStatus(lv ReportLVFull, targetType, layer string) (string, error) {
+	control, err := dm.Open()
+	if err != nil {
+		return "", err
+	}
+	defer control.Close()
+	devices := []dm.Device{}
+	if layer != "" && lv.DMPath != "" {
+		devices = append(devices, dm.Device{Name: filepath.Base(lv.DMPath) + layer})
+	}
+	device, err := deviceForLV(lv)
+	if err != nil {
+		return "", err
+	}
+	devices = append(devices, device)
+	for _, device := range devices {
+		targets, err := control.TableStatus(device)
+		if err != nil {
+			continue
+		}
+		for _, target := range targets {
+			if target.Type == targetType {
+				return target.Params, nil
+			}
+		}
+	}
+	return "", errors.Errorf("no %q target found for LV %q", targetType, lv.FullName)
+}
+
+// ReadThinPoolStatus reads the device-mapper status of an active thin pool.
+func ReadThinPoolStatus(lv ReportLVFull) (dm.ThinPoolStatus, error) {
+	params, err := readTargetStatus(lv, "thin-pool", "-tpool")
+	if err != nil {
+		return dm.ThinPoolStatus{}, err
+	}
+	return dm.ParseThinPoolStatus(params)
+}
+
+// ReadThinStatus reads the device-mapper status of an active thin volume.
+func ReadThinStatus(lv ReportLVFull) (dm.ThinStatus, error) {
+	params, err := readTargetStatus(lv, "thin", "")
+	if err != nil {
+		return dm.ThinStatus{}, err
+	}
+	return dm.ParseThinStatus(params)
+}
+
+// ReadCacheStatus reads the device-mapper status of an active cached volume.
+func ReadCacheStatus(lv ReportLVFull) (dm.CacheStatus, error) {
+	params, err := readTargetStatus(lv, "cache", "")
+	if err != nil {
+		return dm.CacheStatus{}, err
+	}
+	return dm.ParseCacheStatus(params)
+}
+
+// ReadRaidStatus reads the device-mapper status of an active RAID volume.
+func ReadRaidStatus(lv ReportLVFull) (dm.RaidStatus, error) {
+	params, err := readTargetStatus(lv, "raid", "")
+	if err != nil {
+		return dm.RaidStatus{}, err
+	}
+	return dm.ParseRaidStatus(params)
+}
@@ -0,0 +1,364 @@
+// Package dm queries the kernel's device-mapper driver directly, using the
+// ioctl interface of its control device, so that the state of active logical
+// volumes can be read without running lvm or taking its locks.
+package dm
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// ControlPath is the path to the device-mapper control device.
+	ControlPath = "/dev/mapper/control"
+)
+
+const (
+	ioctlHeaderSize = 312
+	nameLength      = 128
+	uuidLength      = 129
+	targetSpecSize  = 40
+	initialBuffer   = 16 * 1024
+
+	cmdVersion     = 0
+	cmdListDevices = 2
+	cmdDevStatus   = 7
+	cmdDevWait     = 8
+	cmdTableStatus = 12
+	cmdTargetMsg   = 14
+	cmdDevArmPoll  = 16
+
+	flagReadOnly        = 1 << 0
+	flagSuspend         = 1 << 1
+	flagStatusTable     = 1 << 4
+	flagActivePresent   = 1 << 5
+	flagInactivePresent = 1 << 6
+	flagBufferFull      = 1 << 8
+	flagNoFlush         = 1 << 11
+	flagDataOut         = 1 << 16
+)
+
+// interfaceVersion is the version of the ioctl interface that we speak.
+var interfaceVersion = [3]uint32{4, 0, 0}
+
+// Device identifies a device-mapper device, either by name or, if the name is
+// empty, by device number.
+type Device struct {
+	Name  string
+	Major uint32
+	Minor uint32
+}
+
+// DeviceInfo is the state of a device-mapper device.
+type DeviceInfo struct {
+	Device
+	UUID            string
+	OpenCount       int32
+	EventNumber     uint32
+	TargetCount     uint32
+	ReadOnly        bool
+	Suspended       bool
+	ActivePresent   bool
+	InactivePresent bool
+}
+
+// Target is one line of a device's table or status: a range of sectors of the
+// device, the type of target which handles them, and either the target's
+// table parameters or its status, depending on which was requested.
+type Target struct {
+	Start  uint64
+	Length uint64
+	Type   string
+	Params string
+}
+
+// Control is an open handle on the device-mapper control device.
+type Control struct {
+	f *os.File
+}
+
+// Open opens the device-mapper control device.
+func Open() (*Control, error) {
+	f, err := os.OpenFile(ControlPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening %q", ControlPath)
+	}
+	return &Control{f: f}, nil
+}
+
+// Close closes the control device.
+func (c *Control) Close() error {
+	return c.f.Close()
+}
+
+// Version returns the version of the kernel's ioctl interface.
+func (c *Control) Version() ([3]uint32, error) {
+	buf, err := c.ioctl(cmdVersion, Device{}, 0, 0, nil)
+	if err != nil {
+		return [3]uint32{}, errors.Wrapf(err, "error reading device-mapper version")
+	}
+	return [3]uint32{
+		binary.LittleEndian.Uint32(buf[0:4]),
+		binary.LittleEndian.Uint32(buf[4:8]),
+		binary.LittleEndian.Uint32(buf[8:12]),
+	}, nil
+}
+
+// ListDevices returns the names and device numbers of all device-mapper
+// devices.
+func (c *Control) ListDevices() ([]Device, error) {
+	buf, err := c.ioctl(cmdListDevices, Device{}, 0, 0, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing device-mapper devices")
+	}
+	data := buf[binary.LittleEndian.Uint32(buf[16:20]):]
+	devices := []Device{}
+	for len(data) >= 12 {
+		dev := binary.LittleEndian.Uint64(data[0:8])
+		next := binary.LittleEndian.Uint32(data[8:12])
+		if dev == 0 {
+			break
+		}
+		devices = append(devices, Device{
+			Name:  cString(data[12:]),
+			Major: unix.Major(dev),
+			Minor: unix.Minor(dev),
+		})
+		if next == 0 || int(next) > len(data) {
+			break
+		}
+		data = data[next:]
+	}
+	return devices, nil
+}
+
+// DeviceStatus returns the state of the specified device.
+func (c *Control) DeviceStatus(d Device) (DeviceInfo, error) {
+	buf, err := c.ioctl(cmdDevStatus, d, 0, 0, nil)
+	if err != nil {
+		return DeviceInfo{}, errors.Wrapf(err, "error reading status of %s", d)
+	}
+	return parseInfo(buf), nil
+}
+
+// TableStatus returns the status of each of the targets in the specified
+// device's active table.  The status is read without flushing outstanding
+// I/O, so that reading it never blocks on a full thin pool.
+func (c *Control) TableStatus(d Device) ([]Target, error) {
+	buf, err := c.ioctl(cmdTableStatus, d, flagNoFlush, 0, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading table status of %s", d)
+	}
+	return parseTargets(buf), nil
+}
+
+// Table returns the specified device's active table.
+func (c *Control) Table(d Device) ([]Target, error) {
+	buf, err := c.ioctl(cmdTableStatus, d, flagStatusTable, 0, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading table of %s", d)
+	}
+	return parseTargets(buf), nil
+}
+
+// WaitEvent blocks until the event counter of the specified device differs
+// from eventNr, and then returns the device's state, including its new event
+// counter, along with the status of each of its targets.  Waiting can not be
+// interrupted, so callers which need to stop waiting should do so after the
+// next event, or use WaitEventContext.
+func (c *Control) WaitEvent(d Device, eventNr uint32) (DeviceInfo, []Target, error) {
+	buf, err := c.ioctl(cmdDevWait, d, flagNoFlush, eventNr, nil)
+	if err != nil {
+		return DeviceInfo{}, nil, errors.Wrapf(err, "error waiting for an event on %s", d)
+	}
+	return parseInfo(buf), parseTargets(buf), nil
+}
+
+// WaitEventContext is like WaitEvent, but stops waiting and returns the
+// context's error when the context is canceled.  It relies on the control
+// device being pollable, which requires version 4.37 of the ioctl interface.
+// With older kernels it falls back to WaitEvent, which can't be canceled.
+func (c *Control) WaitEventContext(ctx context.Context, d Device, eventNr uint32) (DeviceInfo, []Target, error) {
+	cancel, err := cancelPipe(ctx)
+	if err != nil {
+		return DeviceInfo{}, nil, err
+	}
+	defer cancel.close()
+	for {
+		// Arm the control device before checking the counter, so that an
+		// event which happens after we check it wakes up the poll.
+		if _, err = c.ioctl(cmdDevArmPoll, Device{}, 0, 0, nil); err != nil {
+			if err == unix.ENOTTY || err == unix.EINVAL {
+				return c.WaitEvent(d, eventNr)
+			}
+			return DeviceInfo{}, nil, errors.Wrapf(err, "error arming device-mapper events")
+		}
+		buf, err := c.ioctl(cmdTableStatus, d, flagNoFlush, 0, nil)
+		if err != nil {
+			return DeviceInfo{}, nil, errors.Wrapf(err, "error waiting for an event on %s", d)
+		}
+		if info := parseInfo(buf); info.EventNumber != eventNr {
+			return info, parseTargets(buf), nil
+		}
+		fds := []unix.PollFd{
+			{Fd: int32(c.f.Fd()), Events: unix.POLLIN},
+			{Fd: int32(cancel.r), Events: unix.POLLIN},
+		}
+		if _, err = unix.Poll(fds, -1); err != nil && err != unix.EINTR {
+			return DeviceInfo{}, nil, errors.Wrapf(err, "error waiting for an event on %s", d)
+		}
+		if ctx.Err() != nil {
+			return DeviceInfo{}, nil, ctx.Err()
+		}
+	}
+}
+
+// canceler is a pipe which becomes readable when a context is canceled, so
+// that it can be polled along with other descriptors.
+type canceler struct {
+	r, w int
+	stop chan struct{}
+	done chan struct{}
+}
+
+func cancelPipe(ctx context.Context) (*canceler, error) {
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return nil, errors.Wrapf(err, "error creating pipe")
+	}
+	c := &canceler{r: p[0], w: p[1], stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		select {
+		case <-ctx.Done():
+			unix.Write(c.w, []byte{0})
+		case <-c.stop:
+		}
+	}()
+	return c, nil
+}
+
+func (c *canceler) close() {
+	close(c.stop)
+	<-c.done
+	unix.Close(c.r)
+	unix.Close(c.w)
+}
+
+// TargetMessage sends a message to the target which handles the specified
+// sector of a device, and returns the target's response, if it sent one.
+func (c *Control) TargetMessage(d Device, sector uint64, message string) (string, error) {
+	payload := make([]byte, 8+len(message)+1)
+	binary.LittleEndian.PutUint64(payload[0:8], sector)
+	copy(payload[8:], message)
+	buf, err := c.ioctl(cmdTargetMsg, d, 0, 0, payload)
+	if err != nil {
+		return "", errors.Wrapf(err, "error sending message %q to %s", message, d)
+	}
+	if binary.LittleEndian.Uint32(buf[28:32])&flagDataOut == 0 {
+		return "", nil
+	}
+	return cString(buf[binary.LittleEndian.Uint32(buf[16:20]):]), nil
+}
+
+// ioctl issues a device-mapper ioctl for a device, retrying with a larger
+// buffer if the kernel reports that the results didn't fit, and returns the
+// buffer holding the results.
+func (c *Control) ioctl(cmd uintptr, d Device, flags, eventNr uint32, payload []byte) ([]byte, error) {
+	if len(d.Name) >= nameLength {
+		return nil, errors.Errorf("device name %q is too long", d.Name)
+	}
+	size := initialBuffer
+	if size < ioctlHeaderSize+len(payload) {
+		size = ioctlHeaderSize + len(payload)
+	}
+	for {
+		buf := make([]byte, size)
+		for i, v := range interfaceVersion {
+			binary.LittleEndian.PutUint32(buf[i*4:], v)
+		}
+		binary.LittleEndian.PutUint32(buf[12:16], uint32(size))
+		binary.LittleEndian.PutUint32(buf[16:20], ioctlHeaderSize)
+		binary.LittleEndian.PutUint32(buf[28:32], flags)
+		binary.LittleEndian.PutUint32(buf[32:36], eventNr)
+		if d.Name != "" {
+			copy(buf[48:48+nameLength], d.Name)
+		} else if d.Major != 0 || d.Minor != 0 {
+			binary.LittleEndian.PutUint64(buf[40:48], unix.Mkdev(d.Major, d.Minor))
+		}
+		copy(buf[ioctlHeaderSize:], payload)
+		req := uintptr(3<<30|ioctlHeaderSize<<16|0xfd<<8) | cmd
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, c.f.Fd(), req, uintptr(unsafe.Pointer(&buf[0])))
+		if errno != 0 {
+			return nil, errno
+		}
+		if binary.LittleEndian.Uint32(buf[28:32])&flagBufferFull != 0 {
+			size *= 2
+			continue
+		}
+		return buf, nil
+	}
+}
+
+func parseInfo(buf []byte) DeviceInfo {
+	flags := binary.LittleEndian.Uint32(buf[28:32])
+	dev := binary.LittleEndian.Uint64(buf[40:48])
+	return DeviceInfo{
+		Device: Device{
+			Name:  cString(buf[48 : 48+nameLength]),
+			Major: unix.Major(dev),
+			Minor: unix.Minor(dev),
+		},
+		UUID:            cString(buf[48+nameLength : 48+nameLength+uuidLength]),
+		TargetCount:     binary.LittleEndian.Uint32(buf[20:24]),
+		OpenCount:       int32(binary.LittleEndian.Uint32(buf[24:28])),
+		EventNumber:     binary.LittleEndian.Uint32(buf[32:36]),
+		ReadOnly:        flags&flagReadOnly != 0,
+		Suspended:       flags&flagSuspend != 0,
+		ActivePresent:   flags&flagActivePresent != 0,
+		InactivePresent: flags&flagInactivePresent != 0,
+	}
+}
+
+// parseTargets reads the list of target specifications which follows the
+// header in the results of a table status request.  Each specification's
+// "next" field is the offset of the following one from the start of the
+// data.
+func parseTargets(buf []byte) []Target {
+	count := binary.LittleEndian.Uint32(buf[20:24])
+	data := buf[binary.LittleEndian.Uint32(buf[16:20]):]
+	targets := []Target{}
+	offset := uint32(0)
+	for i := uint32(0); i < count && int(offset)+targetSpecSize <= len(data); i++ {
+		spec := data[offset:]
+		targets = append(targets, Target{
+			Start:  binary.LittleEndian.Uint64(spec[0:8]),
+			Length: binary.LittleEndian.Uint64(spec[8:16]),
+			Type:   cString(spec[24:40]),
+			Params: cString(spec[targetSpecSize:]),
+		})
+		offset = binary.LittleEndian.Uint32(spec[20:24])
+	}
+	return targets
+}
+
+func (d Device) String() string {
+	if d.Name != "" {
+		return fmt.Sprintf("device %q", d.Name)
+	}
+	return fmt.Sprintf("device %d:%d", d.Major, d.Minor)
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i != -1 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,25 @@
+package dm
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCancelPipe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c, err := cancelPipe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	fds := []unix.PollFd{{Fd: int32(c.r), Events: unix.POLLIN}}
+	if n, err := unix.Poll(fds, 0); err != nil || n != 0 {
+		t.Fatalf("expected the pipe to not be readable before canceling, got %d, %v", n, err)
+	}
+	cancel()
+	if n, err := unix.Poll(fds, 5000); err != nil || n != 1 {
+		t.Fatalf("expected the pipe to be readable after canceling, got %d, %v", n, err)
+	}
+}
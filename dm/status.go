@@ -0,0 +1,251 @@
+package dm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ThinPoolStatus is the parsed status line of a "thin-pool" target.  Block
+// counts for data are in units of the pool's chunk size, and block counts for
+// metadata are in units of 4KiB.
+type ThinPoolStatus struct {
+	// Fail is set if the pool has failed, in which case nothing else is
+	// reported.
+	Fail                 bool
+	TransactionID        uint64
+	UsedMetadataBlocks   uint64
+	TotalMetadataBlocks  uint64
+	UsedDataBlocks       uint64
+	TotalDataBlocks      uint64
+	HeldMetadataRoot     string
+	ReadOnly             bool
+	OutOfDataSpace       bool
+	DiscardPassdown      bool
+	ErrorIfNoSpace       bool
+	NeedsCheck           bool
+	MetadataLowWatermark uint64
+}
+
+// ThinStatus is the parsed status line of a "thin" target.
+type ThinStatus struct {
+	Fail                bool
+	MappedSectors       uint64
+	HighestMappedSector int64
+}
+
+// CacheStatus is the parsed status line of a "cache" target.
+type CacheStatus struct {
+	Fail                bool
+	MetadataBlockSize   uint64
+	UsedMetadataBlocks  uint64
+	TotalMetadataBlocks uint64
+	CacheBlockSize      uint64
+	UsedCacheBlocks     uint64
+	TotalCacheBlocks    uint64
+	ReadHits            uint64
+	ReadMisses          uint64
+	WriteHits           uint64
+	WriteMisses         uint64
+	Demotions           uint64
+	Promotions          uint64
+	Dirty               uint64
+	Features            []string
+	CoreArgs            []string
+	Policy              string
+	PolicyArgs          []string
+	MetadataMode        string
+	NeedsCheck          bool
+}
+
+// RaidStatus is the parsed status line of a "raid" target.
+type RaidStatus struct {
+	RaidType      string
+	Devices       int
+	Health        string
+	SyncCurrent   uint64
+	SyncTotal     uint64
+	SyncAction    string
+	MismatchCount uint64
+}
+
+// SyncPercent returns how far along the array's synchronization is.
+func (s RaidStatus) SyncPercent() float64 {
+	if s.SyncTotal == 0 {
+		return 100
+	}
+	return float64(s.SyncCurrent) * 100 / float64(s.SyncTotal)
+}
+
+// ParseThinPoolStatus parses the status of a "thin-pool" target.
+func ParseThinPoolStatus(params string) (ThinPoolStatus, error) {
+	f := strings.Fields(params)
+	if len(f) == 1 && (f[0] == "Fail" || f[0] == "Error") {
+		return ThinPoolStatus{Fail: true}, nil
+	}
+	if len(f) < 5 {
+		return ThinPoolStatus{}, errors.Errorf("thin-pool status %q is too short", params)
+	}
+	s := ThinPoolStatus{HeldMetadataRoot: f[3]}
+	var err error
+	if s.TransactionID, err = strconv.ParseUint(f[0], 10, 64); err != nil {
+		return ThinPoolStatus{}, errors.Wrapf(err, "error parsing transaction ID in thin-pool status %q", params)
+	}
+	if s.UsedMetadataBlocks, s.TotalMetadataBlocks, err = parseRatio(f[1]); err != nil {
+		return ThinPoolStatus{}, errors.Wrapf(err, "error parsing metadata usage in thin-pool status %q", params)
+	}
+	if s.UsedDataBlocks, s.TotalDataBlocks, err = parseRatio(f[2]); err != nil {
+		return ThinPoolStatus{}, errors.Wrapf(err, "error parsing data usage in thin-pool status %q", params)
+	}
+	for _, word := range f[4:] {
+		switch word {
+		case "ro":
+			s.ReadOnly = true
+		case "out_of_data_space":
+			s.OutOfDataSpace = true
+		case "discard_passdown":
+			s.DiscardPassdown = true
+		case "error_if_no_space":
+			s.ErrorIfNoSpace = true
+		case "needs_check":
+			s.NeedsCheck = true
+		default:
+			if n, err := strconv.ParseUint(word, 10, 64); err == nil {
+				s.MetadataLowWatermark = n
+			}
+		}
+	}
+	return s, nil
+}
+
+// ParseThinStatus parses the status of a "thin" target.
+func ParseThinStatus(params string) (ThinStatus, error) {
+	f := strings.Fields(params)
+	if len(f) == 1 && f[0] == "Fail" {
+		return ThinStatus{Fail: true}, nil
+	}
+	if len(f) < 2 {
+		return ThinStatus{}, errors.Errorf("thin status %q is too short", params)
+	}
+	s := ThinStatus{HighestMappedSector: -1}
+	var err error
+	if s.MappedSectors, err = strconv.ParseUint(f[0], 10, 64); err != nil {
+		return ThinStatus{}, errors.Wrapf(err, "error parsing mapped sectors in thin status %q", params)
+	}
+	if f[1] != "-" {
+		if s.HighestMappedSector, err = strconv.ParseInt(f[1], 10, 64); err != nil {
+			return ThinStatus{}, errors.Wrapf(err, "error parsing highest mapped sector in thin status %q", params)
+		}
+	}
+	return s, nil
+}
+
+// ParseCacheStatus parses the status of a "cache" target.
+func ParseCacheStatus(params string) (CacheStatus, error) {
+	f := strings.Fields(params)
+	if len(f) == 1 && f[0] == "Fail" {
+		return CacheStatus{Fail: true}, nil
+	}
+	if len(f) < 12 {
+		return CacheStatus{}, errors.Errorf("cache status %q is too short", params)
+	}
+	s := CacheStatus{}
+	var err error
+	if s.MetadataBlockSize, err = strconv.ParseUint(f[0], 10, 64); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing cache status %q", params)
+	}
+	if s.UsedMetadataBlocks, s.TotalMetadataBlocks, err = parseRatio(f[1]); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing cache status %q", params)
+	}
+	if s.CacheBlockSize, err = strconv.ParseUint(f[2], 10, 64); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing cache status %q", params)
+	}
+	if s.UsedCacheBlocks, s.TotalCacheBlocks, err = parseRatio(f[3]); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing cache status %q", params)
+	}
+	counters := []*uint64{&s.ReadHits, &s.ReadMisses, &s.WriteHits, &s.WriteMisses, &s.Demotions, &s.Promotions, &s.Dirty}
+	for i, counter := range counters {
+		if *counter, err = strconv.ParseUint(f[4+i], 10, 64); err != nil {
+			return CacheStatus{}, errors.Wrapf(err, "error parsing cache status %q", params)
+		}
+	}
+	rest := f[11:]
+	if s.Features, rest, err = parseCountedList(rest); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing features in cache status %q", params)
+	}
+	if s.CoreArgs, rest, err = parseCountedList(rest); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing core arguments in cache status %q", params)
+	}
+	if len(rest) == 0 {
+		return CacheStatus{}, errors.Errorf("cache status %q has no policy", params)
+	}
+	s.Policy = rest[0]
+	if s.PolicyArgs, rest, err = parseCountedList(rest[1:]); err != nil {
+		return CacheStatus{}, errors.Wrapf(err, "error parsing policy arguments in cache status %q", params)
+	}
+	if len(rest) > 0 {
+		s.MetadataMode = rest[0]
+	}
+	if len(rest) > 1 {
+		s.NeedsCheck = rest[1] == "needs_check"
+	}
+	return s, nil
+}
+
+// ParseRaidStatus parses the status of a "raid" target.
+func ParseRaidStatus(params string) (RaidStatus, error) {
+	f := strings.Fields(params)
+	if len(f) < 4 {
+		return RaidStatus{}, errors.Errorf("raid status %q is too short", params)
+	}
+	s := RaidStatus{RaidType: f[0], Health: f[2]}
+	var err error
+	if s.Devices, err = strconv.Atoi(f[1]); err != nil {
+		return RaidStatus{}, errors.Wrapf(err, "error parsing device count in raid status %q", params)
+	}
+	if s.SyncCurrent, s.SyncTotal, err = parseRatio(f[3]); err != nil {
+		return RaidStatus{}, errors.Wrapf(err, "error parsing sync ratio in raid status %q", params)
+	}
+	if len(f) > 4 {
+		s.SyncAction = f[4]
+	}
+	if len(f) > 5 {
+		if s.MismatchCount, err = strconv.ParseUint(f[5], 10, 64); err != nil {
+			return RaidStatus{}, errors.Wrapf(err, "error parsing mismatch count in raid status %q", params)
+		}
+	}
+	return s, nil
+}
+
+// parseRatio parses a pair of numbers separated by a slash.
+func parseRatio(s string) (uint64, uint64, error) {
+	i := strings.Index(s, "/")
+	if i == -1 {
+		return 0, 0, errors.Errorf("%q is not a ratio", s)
+	}
+	a, err := strconv.ParseUint(s[:i], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.ParseUint(s[i+1:], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// parseCountedList parses a count followed by that many words.
+func parseCountedList(f []string) ([]string, []string, error) {
+	if len(f) == 0 {
+		return nil, nil, errors.New("missing count")
+	}
+	n, err := strconv.Atoi(f[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if n < 0 || len(f) < n+1 {
+		return nil, nil, errors.Errorf("count %d exceeds the number of values", n)
+	}
+	return f[1 : n+1], f[n+1:], nil
+}
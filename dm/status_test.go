@@ -0,0 +1,69 @@
+package dm
+
+import (
+	"testing"
+)
+
+func TestParseThinPoolStatus(t *testing.T) {
+	s, err := ParseThinPoolStatus("3 97/2048 1523/136512 - rw discard_passdown queue_if_no_space - 1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TransactionID != 3 || s.UsedMetadataBlocks != 97 || s.TotalMetadataBlocks != 2048 || s.UsedDataBlocks != 1523 || s.TotalDataBlocks != 136512 {
+		t.Fatalf("unexpected usage: %+v", s)
+	}
+	if s.ReadOnly || s.OutOfDataSpace || !s.DiscardPassdown || s.ErrorIfNoSpace || s.NeedsCheck || s.MetadataLowWatermark != 1024 {
+		t.Fatalf("unexpected flags: %+v", s)
+	}
+	s, err = ParseThinPoolStatus("3 97/2048 136512/136512 - out_of_data_space no_discard_passdown error_if_no_space needs_check 1024")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.OutOfDataSpace || s.DiscardPassdown || !s.ErrorIfNoSpace || !s.NeedsCheck {
+		t.Fatalf("unexpected flags: %+v", s)
+	}
+	s, err = ParseThinPoolStatus("Fail")
+	if err != nil || !s.Fail {
+		t.Fatalf("expected a failed pool, got %+v, %v", s, err)
+	}
+}
+
+func TestParseThinStatus(t *testing.T) {
+	s, err := ParseThinStatus("131072 262143")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MappedSectors != 131072 || s.HighestMappedSector != 262143 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	s, err = ParseThinStatus("0 -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.MappedSectors != 0 || s.HighestMappedSector != -1 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
+
+func TestParseCacheStatus(t *testing.T) {
+	s, err := ParseCacheStatus("8 31/2048 128 1024/8192 100 20 300 40 5 6 7 1 writethrough 2 migration_threshold 2048 smq 0 rw -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UsedCacheBlocks != 1024 || s.TotalCacheBlocks != 8192 || s.ReadHits != 100 || s.ReadMisses != 20 || s.WriteHits != 300 || s.WriteMisses != 40 || s.Dirty != 7 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if len(s.Features) != 1 || s.Features[0] != "writethrough" || len(s.CoreArgs) != 2 || s.Policy != "smq" || s.MetadataMode != "rw" || s.NeedsCheck {
+		t.Fatalf("unexpected settings: %+v", s)
+	}
+}
+
+func TestParseRaidStatus(t *testing.T) {
+	s, err := ParseRaidStatus("raid1 2 AA 1024/2048 resync 0 0 -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RaidType != "raid1" || s.Devices != 2 || s.Health != "AA" || s.SyncAction != "resync" || s.SyncPercent() != 50 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+}
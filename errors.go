@@ -0,0 +1,122 @@
+package lvm
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommandError is returned when a command that we run fails.
+type CommandError struct {
+	Path     string
+	Args     []string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+// Error returns the error output of the command, or if there wasn't any, the
+// reason that running it failed.
+func (e *CommandError) Error() string {
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		return stderr
+	}
+	return e.Err.Error()
+}
+
+// ExitCode returns the exit status of a command which returned the specified
+// error: 0 if err is nil, or -1 if the command couldn't be run.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if cmdErr, ok := errors.Cause(err).(*CommandError); ok {
+		return cmdErr.ExitCode
+	}
+	return -1
+}
+
+// lockContentionMessages are the messages lvm prints when it fails because
+// another process holds a lock that it needs.
+var lockContentionMessages = []string{
+	"Can't get lock",
+	"Could not lock",
+	"lock failed",
+	"Failed to acquire lock",
+	"already locked",
+	"Resource temporarily unavailable",
+}
+
+// IsLockContention checks if an error was caused by a command failing because
+// another process was holding a lock that it needed.
+func IsLockContention(err error) bool {
+	return Classify(err) == ErrorLockContention
+}
+
+// ErrorClass is a broad category of reasons for which a command can fail.
+type ErrorClass string
+
+const (
+	// ErrorNone is the class of a nil error.
+	ErrorNone = ErrorClass("")
+	// ErrorUnknown is the class of errors which don't fit any other class.
+	ErrorUnknown = ErrorClass("unknown")
+	// ErrorLockContention is the class of failures caused by another process
+	// holding a lock.
+	ErrorLockContention = ErrorClass("lock-contention")
+	// ErrorDeviceBusy is the class of failures caused by a device being open.
+	ErrorDeviceBusy = ErrorClass("device-busy")
+	// ErrorUdev is the class of failures caused by udev not having finished
+	// processing a device.
+	ErrorUdev = ErrorClass("udev")
+	// ErrorNotFound is the class of failures caused by a missing device,
+	// volume group, or logical volume.
+	ErrorNotFound = ErrorClass("not-found")
+	// ErrorNoSpace is the class of failures caused by a lack of free space.
+	ErrorNoSpace = ErrorClass("no-space")
+	// ErrorPermission is the class of failures caused by a lack of
+	// privileges.
+	ErrorPermission = ErrorClass("permission")
+	// ErrorInvalidArgument is the class of failures caused by a command
+	// being run with arguments that it rejects.
+	ErrorInvalidArgument = ErrorClass("invalid-argument")
+)
+
+// errorClassMessages maps classes of errors to the messages which lvm prints
+// for them, in the order in which they're checked.
+var errorClassMessages = []struct {
+	class    ErrorClass
+	messages []string
+}{
+	{ErrorLockContention, lockContentionMessages},
+	// lvm often warns about udev on its way to failing for some other
+	// reason, so the udev messages are checked after the more specific ones.
+	{ErrorNotFound, []string{"not found", "Failed to find", "does not exist", "No such file or directory", "Cannot process volume group"}},
+	{ErrorNoSpace, []string{"Insufficient free space", "Insufficient suitable", "not enough free"}},
+	{ErrorPermission, []string{"Permission denied", "must be root", "Operation not permitted"}},
+	{ErrorDeviceBusy, []string{" in use.", "Device or resource busy", "Can't remove open logical volume", "Unable to deactivate", "is used by another device"}},
+	{ErrorUdev, []string{"not initialized in udev database", "Udev is not running", "udev device handler"}},
+}
+
+// Classify determines the class of an error returned by this package.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ErrorNone
+	}
+	cmdErr, ok := errors.Cause(err).(*CommandError)
+	if !ok {
+		return ErrorUnknown
+	}
+	for _, c := range errorClassMessages {
+		for _, message := range c.messages {
+			if strings.Contains(cmdErr.Stderr, message) {
+				return c.class
+			}
+		}
+	}
+	// lvm exits with status 3 when it doesn't like its command line.
+	if cmdErr.ExitCode == 3 {
+		return ErrorInvalidArgument
+	}
+	return ErrorUnknown
+}
@@ -0,0 +1,216 @@
+package lvm
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Command is a command which a Client runs.
+type Command struct {
+	Path string
+	Args []string
+	// Mutating is true if the command modifies storage.
+	Mutating bool
+	// VGNames lists the volume groups which the command modifies.
+	VGNames []string
+}
+
+// Subcommand returns the lvm subcommand, for example "lvcreate", or the name
+// of the command if it isn't lvm.
+func (c Command) Subcommand() string {
+	if filepath.Base(c.Path) == "lvm" && len(c.Args) > 0 {
+		return c.Args[0]
+	}
+	return filepath.Base(c.Path)
+}
+
+// LVName returns the name of the logical volume which the command modifies,
+// if it names one in one of VGNames.
+func (c Command) LVName() string {
+	for _, arg := range c.Args {
+		for _, vgname := range c.VGNames {
+			if strings.HasPrefix(arg, vgname+"/") {
+				return strings.TrimPrefix(arg, vgname+"/")
+			}
+		}
+	}
+	return ""
+}
+
+// String returns the command line, quoted for use in a shell.
+func (c Command) String() string {
+	words := []string{shellQuote(c.Path)}
+	for _, arg := range c.Args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// Executor runs commands for a Client.
+type Executor interface {
+	// Run runs a command, returning its standard output.
+	Run(cmd Command) (string, error)
+}
+
+type execExecutor struct{}
+
+func (execExecutor) Run(cmd Command) (string, error) {
+	return runWithOutput(cmd.Path, cmd.Args...)
+}
+
+// DefaultExecutor runs commands.  It is used by Clients which don't specify an
+// Executor.
+var DefaultExecutor Executor = execExecutor{}
+
+// DryRunner is implemented by Executors which don't run commands that modify
+// storage.  Operations which modify storage without running a command, like
+// the device-mapper messages which BlockDiff sends, check for it, and refuse to
+// run or skip those changes when it reports true.
+type DryRunner interface {
+	DryRun() bool
+}
+
+// RecordingExecutor records every command that it is asked to run.  Commands
+// which modify storage are not run, and succeed without producing any output.
+// Other commands are passed to Executor, so that reports reflect the current
+// state of the system.  Only commands are recorded: a Client which uses a
+// RecordingExecutor refuses to make changes which can't be expressed as
+// commands.
+type RecordingExecutor struct {
+	// Executor runs commands which don't modify storage.  If it is not set,
+	// DefaultExecutor is used.
+	Executor Executor
+
+	mu       sync.Mutex
+	commands []Command
+}
+
+// Run records a command, and runs it if it doesn't modify storage.
+func (r *RecordingExecutor) Run(cmd Command) (string, error) {
+	r.mu.Lock()
+	r.commands = append(r.commands, cmd)
+	r.mu.Unlock()
+	if cmd.Mutating {
+		return "", nil
+	}
+	if r.Executor != nil {
+		return r.Executor.Run(cmd)
+	}
+	return DefaultExecutor.Run(cmd)
+}
+
+// DryRun returns true, since commands which modify storage are not run.
+func (r *RecordingExecutor) DryRun() bool {
+	return true
+}
+
+// Commands returns the commands which have been recorded.
+func (r *RecordingExecutor) Commands() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Command{}, r.commands...)
+}
+
+// Reset discards the commands which have been recorded.
+func (r *RecordingExecutor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = nil
+}
+
+// WriteScript writes the recorded commands as a shell script which runs the
+// commands which modify storage.  Commands which only read information are
+// included as comments.
+func (r *RecordingExecutor) WriteScript(w io.Writer) error {
+	if _, err := io.WriteString(w, "#!/bin/sh\nset -e\n"); err != nil {
+		return err
+	}
+	for _, cmd := range r.Commands() {
+		line := cmd.String()
+		if !cmd.Mutating {
+			line = "# " + line
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes a word for use in a shell, if it needs to be.
+func shellQuote(word string) string {
+	if word == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range word {
+		if !validNameCharacter(c) && !strings.ContainsRune("/=:,@%", c) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return word
+	}
+	return "'" + strings.Replace(word, "'", `'"'"'`, -1) + "'"
+}
+
+// CommandHook is notified about each command which a HookedExecutor runs.
+type CommandHook interface {
+	// CommandStarted is called before a command is run, and returns a
+	// function which is called with the command's error, if any, after it
+	// has finished.
+	CommandStarted(cmd Command) func(err error)
+}
+
+// HookedExecutor passes commands to another Executor, notifying hooks about
+// each one.
+type HookedExecutor struct {
+	// Executor runs the commands.  If it is not set, DefaultExecutor is
+	// used.
+	Executor Executor
+	Hooks    []CommandHook
+}
+
+// Run runs a command, notifying the hooks before and after.
+func (h *HookedExecutor) Run(cmd Command) (string, error) {
+	finished := make([]func(error), 0, len(h.Hooks))
+	for _, hook := range h.Hooks {
+		finished = append(finished, hook.CommandStarted(cmd))
+	}
+	executor := h.Executor
+	if executor == nil {
+		executor = DefaultExecutor
+	}
+	output, err := executor.Run(cmd)
+	for i := len(finished) - 1; i >= 0; i-- {
+		if finished[i] != nil {
+			finished[i](err)
+		}
+	}
+	return output, err
+}
+
+// DryRun reports whether the Executor which runs the commands is a DryRunner
+// which doesn't run commands that modify storage.
+func (h *HookedExecutor) DryRun() bool {
+	d, ok := h.Executor.(DryRunner)
+	return ok && d.DryRun()
+}
+
+func (c *Client) executor() Executor {
+	if c.Executor != nil {
+		return c.Executor
+	}
+	return DefaultExecutor
+}
+
+// dryRun checks if the Client's Executor doesn't run commands which modify
+// storage.
+func (c *Client) dryRun() bool {
+	d, ok := c.executor().(DryRunner)
+	return ok && d.DryRun()
+}
@@ -0,0 +1,65 @@
+package lvm
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRecordingExecutor(t *testing.T) {
+	script, runs := fakeLVM(t, `{"report": []}`)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+
+	if _, err := c.GetVolumeGroups("vg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateThinVolume("vg", "pool", "layer.a", Size{Bytes: 1024}, "a tag"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveLogicalVolume("vg", "layer.b"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(runs()); n != 1 {
+		t.Fatalf("expected only the report to be run, got %d runs", n)
+	}
+	commands := recorder.Commands()
+	if len(commands) != 3 || commands[0].Mutating || !commands[1].Mutating || !commands[2].Mutating {
+		t.Fatalf("unexpected recorded commands %+v", commands)
+	}
+
+	buf := bytes.Buffer{}
+	if err := recorder.WriteScript(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "#!/bin/sh\nset -e\n" +
+		"# " + script + " vgs --reportformat json --units b --nosuffix vg\n" +
+		script + " lvcreate --thin --virtualsize 1024b --name layer.a --addtag 'a tag' vg/pool\n" +
+		script + " lvremove --force vg/layer.b\n"
+	if buf.String() != expected {
+		t.Fatalf("expected script:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	recorder := &RecordingExecutor{}
+	for _, c := range []*Client{{Executor: recorder}, {Executor: &HookedExecutor{Executor: recorder}}} {
+		if !c.dryRun() {
+			t.Errorf("expected a Client using %T to be in a dry run", c.Executor)
+		}
+	}
+	for _, c := range []*Client{{}, {Executor: &HookedExecutor{}}} {
+		if c.dryRun() {
+			t.Errorf("expected a Client using %T to not be in a dry run", c.Executor)
+		}
+	}
+
+	saved := ThinDeltaPath
+	ThinDeltaPath = "true"
+	defer func() { ThinDeltaPath = saved }()
+	if _, err := (&Client{Executor: recorder}).BlockDiff("vg", "a", "b"); err == nil {
+		t.Errorf("expected BlockDiff to refuse to run in a dry run")
+	}
+	if len(recorder.Commands()) != 0 {
+		t.Errorf("expected BlockDiff to not run any commands in a dry run, got %v", recorder.Commands())
+	}
+}
@@ -0,0 +1,62 @@
+package format
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Units selects how FormatSize writes sizes.
+type Units string
+
+const (
+	// UnitsBytes writes sizes as plain numbers of bytes, which is how lvm
+	// reports them to this package's callers.
+	UnitsBytes = Units("b")
+	// UnitsIEC writes sizes using powers of 1024, for example "1.5GiB".
+	UnitsIEC = Units("iec")
+	// UnitsSI writes sizes using powers of 1000, for example "1.61GB".
+	UnitsSI = Units("si")
+)
+
+var (
+	iecSuffixes = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siSuffixes  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// ParseUnits checks that a string names one of the Units values.
+func ParseUnits(s string) (Units, error) {
+	switch u := Units(strings.ToLower(s)); u {
+	case UnitsBytes, UnitsIEC, UnitsSI:
+		return u, nil
+	}
+	return "", errors.Errorf("unknown units %q: expected %q, %q, or %q", s, UnitsBytes, UnitsIEC, UnitsSI)
+}
+
+// FormatSize writes a number of bytes in the specified units, with up to two
+// decimal places.
+func FormatSize(n int64, units Units) string {
+	var base float64
+	var suffixes []string
+	switch units {
+	case UnitsIEC:
+		base, suffixes = 1024, iecSuffixes
+	case UnitsSI:
+		base, suffixes = 1000, siSuffixes
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+	value, i := math.Abs(float64(n)), 0
+	for value >= base && i < len(suffixes)-1 {
+		value /= base
+		i++
+	}
+	if n < 0 {
+		value = -value
+	}
+	s := strconv.FormatFloat(value, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + suffixes[i]
+}
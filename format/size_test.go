@@ -0,0 +1,27 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	for _, test := range []struct {
+		n        int64
+		units    Units
+		expected string
+	}{
+		{1536, UnitsBytes, "1536"},
+		{0, UnitsIEC, "0B"},
+		{1023, UnitsIEC, "1023B"},
+		{1536, UnitsIEC, "1.5KiB"},
+		{10 << 30, UnitsIEC, "10GiB"},
+		{1 << 62, UnitsIEC, "4EiB"},
+		{1610612736, UnitsSI, "1.61GB"},
+		{999, UnitsSI, "999B"},
+		{-2048, UnitsIEC, "-2KiB"},
+	} {
+		if s := FormatSize(test.n, test.units); s != test.expected {
+			t.Errorf("FormatSize(%d, %q): expected %q, got %q", test.n, test.units, test.expected, s)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package format renders the reports which the lvm package reads as tables or
+// CSV, and writes sizes in human-readable forms.
+package format
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	lvm "github.com/haircommander/lvm-go"
+	"github.com/pkg/errors"
+)
+
+// Kind selects which objects in a report are listed in a table.
+type Kind string
+
+const (
+	// PVs lists physical volumes.
+	PVs = Kind("pv")
+	// VGs lists volume groups.
+	VGs = Kind("vg")
+	// LVs lists logical volumes.
+	LVs = Kind("lv")
+	// Segs lists logical volume segments.  Only full reports include them.
+	Segs = Kind("seg")
+)
+
+// DefaultColumns are the columns which are shown when none are selected,
+// matching the ones which the "pvs", "vgs", "lvs", and "lvs --segments"
+// commands show by default.
+var DefaultColumns = map[Kind][]string{
+	PVs:  {"pv_name", "vg_name", "pv_fmt", "pv_attr", "pv_size", "pv_free"},
+	VGs:  {"vg_name", "pv_count", "lv_count", "snap_count", "vg_attr", "vg_size", "vg_free"},
+	LVs:  {"lv_name", "vg_name", "lv_attr", "lv_size", "pool_lv", "origin", "data_percent", "metadata_percent", "move_pv", "mirror_log", "copy_percent", "convert_lv"},
+	Segs: {"vg_name", "lv_uuid", "segtype", "seg_start", "seg_size", "devices"},
+}
+
+// Options controls which columns a table has and how sizes in it are written.
+type Options struct {
+	// Columns are the names of the fields to show, as lvm names them, for
+	// example "lv_name" or "vg_free".  If none are set, DefaultColumns
+	// are used.
+	Columns []string
+	// Units is how sizes are written.  The default is UnitsBytes.
+	Units Units
+}
+
+// Table is a report, or part of one, as rows of strings.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// vgColumn is the column which full reports don't include in each object, but
+// which we fill in from the volume group that the object is in.
+const vgColumn = "vg_name"
+
+// ReportTable lists objects of the specified kind in a report.
+func ReportTable(report lvm.Report, kind Kind, options Options) (*Table, error) {
+	var rows []interface{}
+	for _, entry := range report.Reports {
+		switch kind {
+		case PVs:
+			for _, pv := range entry.PVs {
+				rows = append(rows, pv)
+			}
+		case VGs:
+			for _, vg := range entry.VGs {
+				rows = append(rows, vg)
+			}
+		case LVs:
+			for _, lv := range entry.LVs {
+				rows = append(rows, lv)
+			}
+		}
+	}
+	return newTable(kind, reportTypes[kind], rows, nil, options)
+}
+
+// FullReportTable lists objects of the specified kind in a full report.
+func FullReportTable(report lvm.ReportFull, kind Kind, options Options) (*Table, error) {
+	var rows []interface{}
+	var vgnames []string
+	for _, entry := range report.Reports {
+		vgname := ""
+		for _, vg := range entry.VGs {
+			vgname = vg.Name
+		}
+		switch kind {
+		case PVs:
+			for _, pv := range entry.PVs {
+				rows, vgnames = append(rows, pv), append(vgnames, vgname)
+			}
+		case VGs:
+			for _, vg := range entry.VGs {
+				rows, vgnames = append(rows, vg), append(vgnames, vgname)
+			}
+		case LVs:
+			for _, lv := range entry.LVs {
+				rows, vgnames = append(rows, lv), append(vgnames, vgname)
+			}
+		case Segs:
+			for _, seg := range entry.Segs {
+				rows, vgnames = append(rows, seg), append(vgnames, vgname)
+			}
+		}
+	}
+	return newTable(kind, fullReportTypes[kind], rows, vgnames, options)
+}
+
+var (
+	reportTypes = map[Kind]reflect.Type{
+		PVs: reflect.TypeOf(lvm.ReportPV{}),
+		VGs: reflect.TypeOf(lvm.ReportVG{}),
+		LVs: reflect.TypeOf(lvm.ReportLV{}),
+	}
+	fullReportTypes = map[Kind]reflect.Type{
+		PVs:  reflect.TypeOf(lvm.ReportPVFull{}),
+		VGs:  reflect.TypeOf(lvm.ReportVGFull{}),
+		LVs:  reflect.TypeOf(lvm.ReportLVFull{}),
+		Segs: reflect.TypeOf(lvm.ReportSegFull{}),
+	}
+)
+
+// fieldIndexes maps the names of the fields in a report type, taken from their
+// JSON tags, to their locations in the type.
+func fieldIndexes(t reflect.Type) map[string][]int {
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			path := append(append([]int{}, index...), i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type, path)
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			// Fields in the outer type take precedence.
+			if _, ok := fields[name]; !ok || len(path) < len(fields[name]) {
+				fields[name] = path
+			}
+		}
+	}
+	walk(t, nil)
+	return fields
+}
+
+// isSizeField checks if a field holds a number of bytes.
+func isSizeField(name string) bool {
+	for _, suffix := range []string{"_size", "_free", "_used", "_start"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTable(kind Kind, t reflect.Type, rows []interface{}, vgnames []string, options Options) (*Table, error) {
+	if t == nil {
+		return nil, errors.Errorf("reports of this type don't include %q objects", kind)
+	}
+	columns := options.Columns
+	if len(columns) == 0 {
+		columns = DefaultColumns[kind]
+	}
+	fields := fieldIndexes(t)
+	for _, column := range columns {
+		if _, ok := fields[column]; !ok && !(column == vgColumn && vgnames != nil) {
+			names := []string{}
+			for name := range fields {
+				names = append(names, name)
+			}
+			if vgnames != nil {
+				names = append(names, vgColumn)
+			}
+			sort.Strings(names)
+			return nil, errors.Errorf("unknown column %q for %q objects, expected one of %s", column, kind, strings.Join(names, ","))
+		}
+	}
+	table := &Table{Columns: append([]string{}, columns...)}
+	for i, row := range rows {
+		v := reflect.ValueOf(row)
+		values := make([]string, 0, len(columns))
+		for _, column := range columns {
+			index, ok := fields[column]
+			if !ok {
+				values = append(values, vgnames[i])
+				continue
+			}
+			values = append(values, formatField(v.FieldByIndex(index), column, options.Units))
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, nil
+}
+
+func formatField(v reflect.Value, name string, units Units) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int64:
+		if isSizeField(name) {
+			return FormatSize(v.Int(), units)
+		}
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.String:
+		if isSizeField(name) {
+			if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+				return FormatSize(n, units)
+			}
+		}
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// WriteTable writes the table with its columns aligned, under a heading.
+func (t *Table) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Columns, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the table as comma-separated values, with a header line.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
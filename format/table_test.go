@@ -0,0 +1,72 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	lvm "github.com/haircommander/lvm-go"
+)
+
+func TestReportTable(t *testing.T) {
+	report := lvm.Report{}
+	data := []byte(`{ "report": [ { "pv": [ {"pv_name":"/dev/vda2", "vg_name":"fedora", "pv_fmt":"lvm2", "pv_attr":"a--", "pv_size":"51921289216", "pv_free":"10737418240"} ] } ] }`)
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	table, err := ReportTable(report, PVs, Options{Units: UnitsIEC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = table.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected table %q", buf.String())
+	}
+	if fields := strings.Join(strings.Fields(lines[0]), " "); fields != "pv_name vg_name pv_fmt pv_attr pv_size pv_free" {
+		t.Errorf("unexpected heading %q", fields)
+	}
+	if fields := strings.Join(strings.Fields(lines[1]), " "); fields != "/dev/vda2 fedora lvm2 a-- 48.36GiB 10GiB" {
+		t.Errorf("unexpected row %q", fields)
+	}
+	if strings.Index(lines[0], "vg_name") != strings.Index(lines[1], "fedora") {
+		t.Errorf("columns are not aligned: %q", buf.String())
+	}
+
+	if _, err = ReportTable(report, Segs, Options{}); err == nil {
+		t.Errorf("expected an error listing segments from a report which can't have them")
+	}
+	if _, err = ReportTable(report, PVs, Options{Columns: []string{"pv_name", "lv_name"}}); err == nil {
+		t.Errorf("expected an error for a column which physical volumes don't have")
+	}
+}
+
+func TestFullReportTable(t *testing.T) {
+	report := lvm.ReportFull{}
+	data := []byte(`{"report": [
+		{"vg": [{"vg_name": "vg1"}], "lv": [{"lv_name": "pool", "lv_size": "1073741824", "lv_metadata_size": "4194304", "lv_tags": "a,b"}, {"lv_name": "thin", "lv_size": "2147483648"}]},
+		{"vg": [{"vg_name": "vg2"}], "lv": [{"lv_name": "root", "lv_size": "512"}]}
+	]}`)
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	table, err := FullReportTable(report, LVs, Options{Columns: []string{"vg_name", "lv_name", "lv_size", "lv_metadata_size", "lv_tags"}, Units: UnitsSI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = table.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := "vg_name,lv_name,lv_size,lv_metadata_size,lv_tags\n" +
+		"vg1,pool,1.07GB,4.19MB,\"a,b\"\n" +
+		"vg1,thin,2.15GB,,\n" +
+		"vg2,root,512B,,\n"
+	if buf.String() != expected {
+		t.Errorf("expected CSV %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,121 @@
+package lvm
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// GCPlan describes what CollectGarbage removes, or would remove.
+type GCPlan struct {
+	// Remove lists the layer volumes which are not live, in the order in
+	// which they are removed: snapshots before their origins.
+	Remove []LayerVolume
+	// Retain lists the layer volumes which are not live, but which are kept
+	// because they are the origins of live layer volumes.
+	Retain []LayerVolume
+}
+
+// CollectGarbage is a wrapper around DefaultClient.CollectGarbage.
+func CollectGarbage(vgname string, live map[string]bool, dryRun bool) (GCPlan, error) {
+	return DefaultClient.CollectGarbage(vgname, live, dryRun)
+}
+
+// CollectGarbage removes the layer volumes in a volume group whose IDs are not
+// in the live set.  Volumes which are the origins, directly or indirectly, of
+// live volumes are kept.  If dryRun is true, nothing is removed, and the plan
+// is only returned.
+func (c *Client) CollectGarbage(vgname string, live map[string]bool, dryRun bool) (GCPlan, error) {
+	layers, err := c.ListLayerVolumes(vgname)
+	if err != nil {
+		return GCPlan{}, err
+	}
+	plan := planGarbageCollection(layers, live)
+	if dryRun {
+		return plan, nil
+	}
+	for i, layer := range plan.Remove {
+		logrus.Debugf("removing unused layer %q (%q)", layer.ID, layer.VGName+"/"+layer.LV.Name)
+		if err = c.RemoveLogicalVolume(layer.VGName, layer.LV.Name); err != nil {
+			plan.Remove = plan.Remove[:i]
+			return plan, errors.Wrapf(err, "error removing unused layer %q", layer.ID)
+		}
+	}
+	return plan, nil
+}
+
+// planGarbageCollection decides which layer volumes to remove, and in what
+// order.
+func planGarbageCollection(layers []LayerVolume, live map[string]bool) GCPlan {
+	byName := map[string]LayerVolume{}
+	children := map[string][]string{}
+	for _, layer := range layers {
+		key := layer.VGName + "/" + layer.LV.Name
+		byName[key] = layer
+		if layer.LV.Origin != "" {
+			origin := layer.VGName + "/" + layer.LV.Origin
+			children[origin] = append(children[origin], key)
+		}
+	}
+
+	// Keep every layer from which a live layer descends.
+	retained := map[string]bool{}
+	for _, layer := range byName {
+		if !live[layer.ID] {
+			continue
+		}
+		for origin := layer.LV.Origin; origin != ""; {
+			key := layer.VGName + "/" + origin
+			parent, ok := byName[key]
+			if !ok || retained[key] {
+				break
+			}
+			retained[key] = true
+			origin = parent.LV.Origin
+		}
+	}
+
+	plan := GCPlan{}
+	doomed := map[string]bool{}
+	keys := []string{}
+	for key, layer := range byName {
+		switch {
+		case live[layer.ID]:
+		case retained[key]:
+			plan.Retain = append(plan.Retain, layer)
+		default:
+			doomed[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	sort.Slice(plan.Retain, func(i, j int) bool {
+		return plan.Retain[i].VGName+"/"+plan.Retain[i].LV.Name < plan.Retain[j].VGName+"/"+plan.Retain[j].LV.Name
+	})
+
+	// Remove each layer only after all of the layers which were created from
+	// it have been removed.
+	removed := map[string]bool{}
+	for progress := true; progress; {
+		progress = false
+		for _, key := range keys {
+			if removed[key] {
+				continue
+			}
+			ready := true
+			for _, child := range children[key] {
+				if doomed[child] && !removed[child] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				plan.Remove = append(plan.Remove, byName[key])
+				removed[key] = true
+				progress = true
+			}
+		}
+	}
+	return plan
+}
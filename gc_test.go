@@ -0,0 +1,36 @@
+package lvm
+
+import (
+	"testing"
+)
+
+func TestPlanGarbageCollection(t *testing.T) {
+	layer := func(id, origin string) LayerVolume {
+		lv := ReportLVFull{}
+		lv.Name = "layer." + id
+		if origin != "" {
+			lv.Origin = "layer." + origin
+		}
+		return LayerVolume{ID: id, VGName: "vg", LV: lv}
+	}
+	layers := []LayerVolume{
+		layer("a", ""),
+		layer("b", "a"),
+		layer("c", "b"),
+		layer("d", ""),
+		layer("e", "d"),
+		layer("f", "e"),
+	}
+	plan := planGarbageCollection(layers, map[string]bool{"c": true})
+
+	if len(plan.Retain) != 2 || plan.Retain[0].ID != "a" || plan.Retain[1].ID != "b" {
+		t.Fatalf("expected the ancestors of \"c\" to be retained, got %+v", plan.Retain)
+	}
+	order := []string{}
+	for _, layer := range plan.Remove {
+		order = append(order, layer.ID)
+	}
+	if len(order) != 3 || order[0] != "f" || order[1] != "e" || order[2] != "d" {
+		t.Fatalf("expected snapshots to be removed before their origins, got %v", order)
+	}
+}
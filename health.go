@@ -0,0 +1,112 @@
+package lvm
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of problems reported by HealthCheck.
+const (
+	HealthMissingPV       = "missing-pv"
+	HealthPartialVG       = "partial-vg"
+	HealthMetadataFull    = "metadata-area-full"
+	HealthCheckNeeded     = "check-needed"
+	HealthLVStatus        = "lv-health"
+	HealthInvalidSnapshot = "invalid-snapshot"
+	HealthRaidMismatch    = "raid-mismatch"
+)
+
+// MetadataAreaWarningPercent is how full, as a percentage, a volume group's
+// metadata areas can be before HealthCheck reports them.
+var MetadataAreaWarningPercent int64 = 90
+
+// HealthProblem is something that HealthCheck found to be wrong.
+type HealthProblem struct {
+	Kind string
+	// Object is the volume group, physical volume, or logical volume, in
+	// "vg/lv" form, which has the problem.
+	Object string
+	Detail string
+}
+
+// HealthReport is the result of HealthCheck.
+type HealthReport struct {
+	VGName   string
+	Problems []HealthProblem
+}
+
+// Healthy checks if no problems were found.
+func (r HealthReport) Healthy() bool {
+	return len(r.Problems) == 0
+}
+
+// HealthCheck is a wrapper around DefaultClient.HealthCheck.
+func HealthCheck(vgname string) (HealthReport, error) {
+	return DefaultClient.HealthCheck(vgname)
+}
+
+// HealthCheck looks for problems with a volume group, its physical volumes,
+// and its logical volumes, including thin pools which need to be checked.  An
+// error is returned only if the volume group couldn't be examined.
+func (c *Client) HealthCheck(vgname string) (HealthReport, error) {
+	report, err := c.GetFullReport(vgname)
+	if err != nil {
+		return HealthReport{}, errors.Wrapf(err, "error reading information about volume group %q", vgname)
+	}
+	return checkHealth(vgname, report), nil
+}
+
+// reportFlagSet checks if a field in a report which is either empty or a
+// description of a condition is set.  lvm reports "unknown" for conditions
+// which it can't determine.
+func reportFlagSet(value string) bool {
+	return value != "" && value != "unknown"
+}
+
+func checkHealth(vgname string, report ReportFull) HealthReport {
+	health := HealthReport{VGName: vgname}
+	add := func(kind, object, detail string) {
+		health.Problems = append(health.Problems, HealthProblem{Kind: kind, Object: object, Detail: detail})
+	}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
+		}
+		name := vgname
+		for _, vg := range entry.VGs {
+			name = vg.Name
+			if vg.MissingPVCount > 0 {
+				add(HealthMissingPV, vg.Name, fmt.Sprintf("%d physical volumes are missing", vg.MissingPVCount))
+			}
+			if reportFlagSet(vg.Partial) {
+				add(HealthPartialVG, vg.Name, "volume group is partial")
+			}
+			if vg.MDASize > 0 && (vg.MDASize-vg.MDAFree)*100 >= vg.MDASize*MetadataAreaWarningPercent {
+				add(HealthMetadataFull, vg.Name, fmt.Sprintf("%d of %d bytes of metadata area are free", vg.MDAFree, vg.MDASize))
+			}
+		}
+		for _, pv := range entry.PVs {
+			if reportFlagSet(pv.Missing) {
+				add(HealthMissingPV, pv.Name, "physical volume is missing")
+			}
+		}
+		for _, lv := range entry.LVs {
+			object := name + "/" + stripBrackets(lv.Name)
+			if reportFlagSet(lv.CheckNeeded) {
+				add(HealthCheckNeeded, object, "metadata needs to be checked")
+			}
+			if reportFlagSet(lv.HealthStatus) {
+				add(HealthLVStatus, object, lv.HealthStatus)
+			}
+			if reportFlagSet(lv.SnapshotInvalid) {
+				add(HealthInvalidSnapshot, object, "snapshot is invalid")
+			}
+			if mismatches, err := strconv.ParseInt(lv.RAIDMismatchCount, 10, 64); err == nil && mismatches > 0 {
+				add(HealthRaidMismatch, object, fmt.Sprintf("%d mismatches found by scrubbing", mismatches))
+			}
+		}
+	}
+	return health
+}
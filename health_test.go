@@ -0,0 +1,48 @@
+package lvm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	report := ReportFull{}
+	data := []byte(`{"report": [{
+		"vg": [{"vg_name": "vg", "vg_missing_pv_count": "1", "vg_partial": "partial", "vg_mda_size": "1044480", "vg_mda_free": "4096"}],
+		"pv": [{"pv_name": "/dev/sdb", "pv_missing": ""}, {"pv_name": "[unknown]", "pv_missing": "missing"}],
+		"lv": [
+			{"lv_name": "pool", "lv_check_needed": "check needed", "lv_health_status": "", "lv_snapshot_invalid": "unknown"},
+			{"lv_name": "mirror", "lv_health_status": "partial", "raid_mismatch_count": "8"},
+			{"lv_name": "snap", "lv_snapshot_invalid": "snapshot invalid", "raid_mismatch_count": ""},
+			{"lv_name": "ok", "lv_check_needed": "unknown", "raid_mismatch_count": "0"}
+		]
+	}]}`)
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	health := checkHealth("vg", report)
+	expected := []HealthProblem{
+		{Kind: HealthMissingPV, Object: "vg"},
+		{Kind: HealthPartialVG, Object: "vg"},
+		{Kind: HealthMetadataFull, Object: "vg"},
+		{Kind: HealthMissingPV, Object: "[unknown]"},
+		{Kind: HealthCheckNeeded, Object: "vg/pool"},
+		{Kind: HealthLVStatus, Object: "vg/mirror"},
+		{Kind: HealthRaidMismatch, Object: "vg/mirror"},
+		{Kind: HealthInvalidSnapshot, Object: "vg/snap"},
+	}
+	if len(health.Problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %+v", len(expected), health.Problems)
+	}
+	for i := range expected {
+		if health.Problems[i].Kind != expected[i].Kind || health.Problems[i].Object != expected[i].Object {
+			t.Errorf("problem %d: expected %+v, got %+v", i, expected[i], health.Problems[i])
+		}
+	}
+	if health.Healthy() {
+		t.Errorf("expected the volume group to be unhealthy")
+	}
+	if !checkHealth("other", report).Healthy() {
+		t.Errorf("expected a volume group that isn't in the report to be healthy")
+	}
+}
@@ -0,0 +1,141 @@
+package lvm
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VolumePathResult is the result of looking up the device pathname for one of
+// the IDs passed to VolumePathsForIDs.
+type VolumePathResult struct {
+	Path string
+	Err  error
+}
+
+// LayerVolume is a logical volume whose name was produced by a Namer.
+type LayerVolume struct {
+	ID     string
+	VGName string
+	LV     ReportLVFull
+}
+
+// entryInVolumeGroup checks if a report entry describes the named volume
+// group.  Every entry matches if vgname is empty.
+func entryInVolumeGroup(entry ReportEntryFull, vgname string) bool {
+	if vgname == "" {
+		return true
+	}
+	for _, vg := range entry.VGs {
+		if vg.Name == vgname {
+			return true
+		}
+	}
+	return false
+}
+
+// activeVolumePath returns whichever of a logical volume's device pathnames
+// exists.
+func activeVolumePath(vgname string, lv ReportLVFull) (string, error) {
+	for _, path := range []string{lv.DMPath, lv.Path} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.Errorf("found LV %q, but no active path for it", vgname+"/"+lv.Name)
+}
+
+// VolumePathsForIDs is a wrapper around DefaultClient.VolumePathsForIDs.
+func VolumePathsForIDs(vgname string, ids []string) (map[string]VolumePathResult, error) {
+	return DefaultClient.VolumePathsForIDs(vgname, ids)
+}
+
+// VolumePathsForIDs determines the device pathnames for the volumes with each
+// of the specified IDs in a particular volume group, or across all volume
+// groups, using a single report.  An error is returned only if the report
+// can't be read; failures to find a particular volume are recorded in that
+// ID's result.
+func (c *Client) VolumePathsForIDs(vgname string, ids []string) (map[string]VolumePathResult, error) {
+	report, err := c.GetFullReport(vgname)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	results := map[string]VolumePathResult{}
+	names := map[string]string{}
+	wanted := map[string]string{}
+	for _, id := range ids {
+		name, _, err := c.namer().VolumeName(id)
+		if err != nil {
+			results[id] = VolumePathResult{Err: err}
+			continue
+		}
+		names[id] = name
+		wanted[name] = id
+	}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
+		}
+		for _, lv := range entry.LVs {
+			id, ok := wanted[lv.Name]
+			if !ok {
+				continue
+			}
+			if _, found := results[id]; found {
+				continue
+			}
+			path, err := activeVolumePath(vgname, lv)
+			results[id] = VolumePathResult{Path: path, Err: err}
+		}
+	}
+	for _, id := range ids {
+		if _, found := results[id]; !found {
+			results[id] = VolumePathResult{Err: errors.Errorf("no LV named %q found", vgname+"/"+names[id])}
+		}
+	}
+	return results, nil
+}
+
+// ListLayerVolumes is a wrapper around DefaultClient.ListLayerVolumes.
+func ListLayerVolumes(vgname string) ([]LayerVolume, error) {
+	return DefaultClient.ListLayerVolumes(vgname)
+}
+
+// ListLayerVolumes returns every logical volume in a particular volume group,
+// or across all volume groups, whose name was produced by the Client's Namer,
+// sorted by volume group and ID.
+func (c *Client) ListLayerVolumes(vgname string) ([]LayerVolume, error) {
+	report, err := c.GetFullReport(vgname)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	layers := []LayerVolume{}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
+		}
+		for _, lv := range entry.LVs {
+			id, ok := c.namer().ID(lv.Name, splitTags(lv.Tags)...)
+			if !ok {
+				continue
+			}
+			vg := vgname
+			if i := strings.Index(lv.FullName, "/"); i != -1 {
+				vg = lv.FullName[:i]
+			}
+			layers = append(layers, LayerVolume{ID: id, VGName: vg, LV: lv})
+		}
+	}
+	sort.Slice(layers, func(i, j int) bool {
+		if layers[i].VGName != layers[j].VGName {
+			return layers[i].VGName < layers[j].VGName
+		}
+		return layers[i].ID < layers[j].ID
+	})
+	return layers, nil
+}
@@ -0,0 +1,42 @@
+package lvm
+
+import (
+	"testing"
+)
+
+func TestLayerVolumes(t *testing.T) {
+	script, runs := fakeLVM(t, `{"report": [{"vg": [{"vg_name": "vg"}], "lv": [
+		{"lv_name": "layer.a", "lv_full_name": "vg/layer.a", "lv_path": "/nonexistent/vg/layer.a"},
+		{"lv_name": "layer.b", "lv_full_name": "vg/layer.b", "lv_path": "/dev/null"},
+		{"lv_name": "pool", "lv_full_name": "vg/pool"}
+	]}]}`)
+	c := &Client{LVMPath: script}
+
+	results, err := c.VolumePathsForIDs("vg", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+	if results["a"].Err == nil {
+		t.Errorf("expected an error for an LV with no active path, got %q", results["a"].Path)
+	}
+	if results["b"].Err != nil || results["b"].Path != "/dev/null" {
+		t.Errorf("expected \"/dev/null\" for \"b\", got %+v", results["b"])
+	}
+	if results["c"].Err == nil {
+		t.Errorf("expected an error for a missing LV, got %q", results["c"].Path)
+	}
+	if n := len(runs()); n != 1 {
+		t.Fatalf("expected 1 run of lvm, got %d", n)
+	}
+
+	layers, err := c.ListLayerVolumes("vg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 2 || layers[0].ID != "a" || layers[1].ID != "b" || layers[0].VGName != "vg" {
+		t.Fatalf("unexpected layer volumes: %+v", layers)
+	}
+}
@@ -0,0 +1,227 @@
+package lvm
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// LayerStore manages layers which are stored as thin logical volumes in a
+// thin pool, with each layer which has a parent being a thin snapshot of its
+// parent's volume.  Volumes are activated as needed.
+type LayerStore struct {
+	// Client is used to run lvm.  If it is not set, DefaultClient is used.
+	Client   *Client
+	VGName   string
+	PoolName string
+	// History records the pool that the store was opened with.
+	History LvmPoolHistory
+}
+
+// NewLayerStore returns a LayerStore which keeps layers in the specified thin
+// pool.
+func NewLayerStore(client *Client, vgname, poolname string) (*LayerStore, error) {
+	s := &LayerStore{Client: client, VGName: vgname, PoolName: poolname}
+	history, err := s.client().ReadPoolInfo(vgname, poolname)
+	if err != nil {
+		return nil, err
+	}
+	s.History = history
+	return s, nil
+}
+
+func (s *LayerStore) client() *Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return DefaultClient
+}
+
+// VerifyPool checks that the thin pool is still the one that the store was
+// opened with.  If it has been replaced, the set of layers that it holds
+// likely no longer matches what callers think we have.
+func (s *LayerStore) VerifyPool() error {
+	history, err := s.client().ReadPoolInfo(s.VGName, s.PoolName)
+	if err != nil {
+		return err
+	}
+	if s.History.PoolUUID != "" && history.PoolUUID != s.History.PoolUUID {
+		return errors.Errorf("thin pool %q has changed UUID from %q to %q", s.VGName+"/"+s.PoolName, s.History.PoolUUID, history.PoolUUID)
+	}
+	return nil
+}
+
+// volumes reads the logical volumes in the volume group, indexed by name.
+func (s *LayerStore) volumes() (map[string]ReportLVFull, error) {
+	report, err := s.client().GetFullReport(s.VGName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading information about volume group %q", s.VGName)
+	}
+	volumes := map[string]ReportLVFull{}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, s.VGName) {
+			continue
+		}
+		for _, lv := range entry.LVs {
+			volumes[lv.Name] = lv
+		}
+	}
+	return volumes, nil
+}
+
+// find locates the logical volume for a layer.
+func (s *LayerStore) find(id string) (ReportLVFull, bool, error) {
+	name, _, err := s.client().namer().VolumeName(id)
+	if err != nil {
+		return ReportLVFull{}, false, err
+	}
+	volumes, err := s.volumes()
+	if err != nil {
+		return ReportLVFull{}, false, err
+	}
+	lv, ok := volumes[name]
+	if ok && stripBrackets(lv.PoolLV) != s.PoolName {
+		return ReportLVFull{}, false, errors.Errorf("LV %q is not in thin pool %q", s.VGName+"/"+name, s.PoolName)
+	}
+	return lv, ok, nil
+}
+
+// Create creates a layer with the specified ID, as a snapshot of the parent
+// layer if parent is not empty, and returns the path of its device.  If size
+// is larger than the parent layer, the new layer is extended to that size.
+// Since layers can't be smaller than their parents, a smaller size is an
+// error, while zero means the parent's size.
+func (s *LayerStore) Create(id, parent string, size int64) (string, error) {
+	c := s.client()
+	name, tags, err := c.namer().VolumeName(id)
+	if err != nil {
+		return "", err
+	}
+	if _, exists, err := s.find(id); err != nil {
+		return "", err
+	} else if exists {
+		return "", errors.Errorf("layer %q already exists", id)
+	}
+	if parent == "" {
+		if size <= 0 {
+			return "", errors.Errorf("no size specified for layer %q", id)
+		}
+		if err = c.CreateThinVolume(s.VGName, s.PoolName, name, Size{Bytes: size}, tags...); err != nil {
+			return "", err
+		}
+	} else {
+		origin, exists, err := s.find(parent)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", errors.Errorf("parent layer %q of layer %q does not exist", parent, id)
+		}
+		if size > 0 && size < origin.Size {
+			return "", errors.Errorf("layer %q can't be %d bytes, which is smaller than its parent layer %q", id, size, parent)
+		}
+		if err = c.CreateThinSnapshot(s.VGName, origin.Name, name, tags...); err != nil {
+			return "", err
+		}
+		if size > origin.Size {
+			if err = c.ExtendLogicalVolume(s.VGName, name, Size{Bytes: size}); err != nil {
+				if removeErr := c.RemoveLogicalVolume(s.VGName, name); removeErr != nil {
+					logrus.Errorf("error removing partially created layer %q: %v", id, removeErr)
+				}
+				return "", err
+			}
+		}
+	}
+	return s.Get(id)
+}
+
+// Remove removes the layer with the specified ID.  Layers which are the
+// parents of other layers can not be removed.
+func (s *LayerStore) Remove(id string) error {
+	lv, exists, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("layer %q does not exist", id)
+	}
+	volumes, err := s.volumes()
+	if err != nil {
+		return err
+	}
+	for _, child := range volumes {
+		if child.Origin == lv.Name && stripBrackets(child.PoolLV) == s.PoolName {
+			return errors.Errorf("layer %q is the parent of LV %q", id, s.VGName+"/"+child.Name)
+		}
+	}
+	return s.client().RemoveLogicalVolume(s.VGName, lv.Name)
+}
+
+// Get returns the path of the device for the layer with the specified ID,
+// activating it if it isn't already active.
+func (s *LayerStore) Get(id string) (string, error) {
+	lv, exists, err := s.find(id)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.Errorf("layer %q does not exist", id)
+	}
+	if !lv.IsActive() {
+		if err = s.client().ActivateLogicalVolume(s.VGName, lv.Name); err != nil {
+			return "", err
+		}
+		if lv, _, err = s.find(id); err != nil {
+			return "", err
+		}
+	}
+	return activeVolumePath(s.VGName, lv)
+}
+
+// Exists checks if there is a layer with the specified ID.
+func (s *LayerStore) Exists(id string) bool {
+	_, exists, err := s.find(id)
+	return err == nil && exists
+}
+
+// List returns the layers in the thin pool.
+func (s *LayerStore) List() ([]LayerVolume, error) {
+	layers, err := s.client().ListLayerVolumes(s.VGName)
+	if err != nil {
+		return nil, err
+	}
+	inPool := []LayerVolume{}
+	for _, layer := range layers {
+		if stripBrackets(layer.LV.PoolLV) == s.PoolName {
+			inPool = append(inPool, layer)
+		}
+	}
+	return inPool, nil
+}
+
+// Parent returns the ID of the layer that the layer with the specified ID is
+// a snapshot of, or an empty string if it is not a snapshot of a layer.
+func (s *LayerStore) Parent(id string) (string, error) {
+	lv, exists, err := s.find(id)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.Errorf("layer %q does not exist", id)
+	}
+	if lv.Origin == "" {
+		return "", nil
+	}
+	volumes, err := s.volumes()
+	if err != nil {
+		return "", err
+	}
+	origin, ok := volumes[lv.Origin]
+	if !ok {
+		return "", errors.Errorf("origin %q of layer %q not found", s.VGName+"/"+lv.Origin, id)
+	}
+	parent, ok := s.client().namer().ID(origin.Name, splitTags(origin.Tags)...)
+	if !ok {
+		return "", nil
+	}
+	return parent, nil
+}
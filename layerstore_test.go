@@ -0,0 +1,77 @@
+package lvm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayerStore(t *testing.T) {
+	script, _ := fakeLVM(t, `{"report": [{"vg": [{"vg_name": "vg"}], "lv": [
+		{"lv_name": "pool", "lv_uuid": "pool-uuid", "lv_attr": "twi-a-tz--"},
+		{"lv_name": "layer.base", "lv_full_name": "vg/layer.base", "lv_size": "4096", "pool_lv": "pool", "lv_attr": "Vwi-a-tz--", "lv_path": "/dev/null"},
+		{"lv_name": "layer.child", "lv_full_name": "vg/layer.child", "pool_lv": "pool", "origin": "layer.base", "lv_attr": "Vwi-a-tz--", "lv_path": "/dev/null"},
+		{"lv_name": "layer.other", "lv_full_name": "vg/layer.other", "pool_lv": "pool2", "lv_attr": "Vwi-a-tz--"}
+	]}]}`)
+	s, err := NewLayerStore(&Client{LVMPath: script}, "vg", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.History.PoolUUID != "pool-uuid" {
+		t.Fatalf("unexpected pool history %+v", s.History)
+	}
+
+	if !s.Exists("base") || s.Exists("missing") || s.Exists("other") {
+		t.Fatalf("unexpected results from Exists()")
+	}
+	layers, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 2 || layers[0].ID != "base" || layers[1].ID != "child" {
+		t.Fatalf("unexpected layers %+v", layers)
+	}
+	parent, err := s.Parent("child")
+	if err != nil || parent != "base" {
+		t.Fatalf("expected parent \"base\", got %q (%v)", parent, err)
+	}
+	if parent, err = s.Parent("base"); err != nil || parent != "" {
+		t.Fatalf("expected no parent, got %q (%v)", parent, err)
+	}
+	if path, err := s.Get("child"); err != nil || path != "/dev/null" {
+		t.Fatalf("expected \"/dev/null\", got %q (%v)", path, err)
+	}
+	if err = s.Remove("base"); err == nil {
+		t.Fatalf("expected an error removing a layer with children")
+	}
+	if _, err = s.Create("base", "", 1024); err == nil {
+		t.Fatalf("expected an error creating a layer that already exists")
+	}
+
+	// A layer can't be smaller than its parent.
+	recorder := &RecordingExecutor{}
+	s.Client.Executor = recorder
+	if _, err = s.Create("new", "base", 1024); err == nil {
+		t.Fatalf("expected an error creating a layer smaller than its parent")
+	}
+	for _, cmd := range recorder.Commands() {
+		if cmd.Mutating {
+			t.Fatalf("expected no changes for a layer smaller than its parent, got %v", cmd)
+		}
+	}
+
+	// A snapshot which can't be extended is removed.
+	recorder.Reset()
+	s.Client.Executor = failingExecutor{Executor: recorder, fail: "lvextend"}
+	if _, err = s.Create("new", "base", 8192); err == nil {
+		t.Fatalf("expected an error when the new layer can't be extended")
+	}
+	subcommands := []string{}
+	for _, cmd := range recorder.Commands() {
+		if cmd.Mutating {
+			subcommands = append(subcommands, cmd.Subcommand())
+		}
+	}
+	if strings.Join(subcommands, " ") != "lvcreate lvremove" {
+		t.Fatalf("expected the snapshot to be removed, got commands %q", subcommands)
+	}
+}
@@ -0,0 +1,124 @@
+package lvm
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// LockManager serializes changes to volume groups, both between goroutines
+// and, if Dir is set, between processes which use the same directory.  Changes
+// which don't name a volume group, like creating a physical volume, exclude
+// all other changes, since they can race with changes to any volume group.
+type LockManager struct {
+	// Dir is the directory in which lock files are kept.  If it is empty,
+	// locking only excludes other users of the same LockManager.  Lock files
+	// are named with a prefix so that they don't collide with lvm's, but Dir
+	// should still not be lvm's own locking_dir (usually /run/lock/lvm),
+	// which lvm expects to manage by itself.
+	Dir string
+
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+// NewLockManager returns a LockManager which keeps lock files in the
+// specified directory.
+func NewLockManager(dir string) *LockManager {
+	return &LockManager{Dir: dir}
+}
+
+// lockFilePrefix is prepended to the names of lock files, so that they can't
+// be mistaken for the "V_<vgname>" files which lvm uses for its own locks.
+const lockFilePrefix = "lvm-go.V_"
+
+// globalLock is the name of the lock which is held exclusively for commands
+// which don't operate on a particular volume group, and shared for ones which
+// do.
+const globalLock = "#global"
+
+func (m *LockManager) mutex(name string) *sync.RWMutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = make(map[string]*sync.RWMutex)
+	}
+	if m.locks[name] == nil {
+		m.locks[name] = &sync.RWMutex{}
+	}
+	return m.locks[name]
+}
+
+// lockOne acquires a single lock, either exclusively or shared with other
+// holders which don't need it exclusively.
+func (m *LockManager) lockOne(name string, exclusive bool) (func(), error) {
+	rw := m.mutex(name)
+	lock, unlock, how := rw.RLock, rw.RUnlock, unix.LOCK_SH
+	if exclusive {
+		lock, unlock, how = rw.Lock, rw.Unlock, unix.LOCK_EX
+	}
+	lock()
+	if m.Dir == "" {
+		return unlock, nil
+	}
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		unlock()
+		return nil, errors.Wrapf(err, "error creating lock directory %q", m.Dir)
+	}
+	path := filepath.Join(m.Dir, lockFilePrefix+strings.Replace(name, "/", "_", -1))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		unlock()
+		return nil, errors.Wrapf(err, "error opening lock file %q", path)
+	}
+	if err = unix.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		unlock()
+		return nil, errors.Wrapf(err, "error locking %q", path)
+	}
+	return func() {
+		unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		f.Close()
+		unlock()
+	}, nil
+}
+
+// Lock acquires the global lock, exclusively if no volume groups are
+// specified and shared otherwise, and then the locks for the specified volume
+// groups in a consistent order, and returns a function which releases them.
+func (m *LockManager) Lock(vgname ...string) (func(), error) {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range vgname {
+		if name != "" && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	sort.Strings(names)
+	unlocks := []func(){}
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	u, err := m.lockOne(globalLock, len(names) == 0)
+	if err != nil {
+		return nil, err
+	}
+	unlocks = append(unlocks, u)
+	for _, name := range names {
+		u, err := m.lockOne(name, true)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, u)
+	}
+	return unlock, nil
+}
@@ -0,0 +1,146 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	m := NewLockManager(filepath.Join(dir, "locks"))
+
+	held, running, max := 0, 0, 0
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vgnames := []string{"vg1", "vg2"}
+			if i%2 == 1 {
+				vgnames = []string{"vg2", "vg1"}
+			}
+			unlock, err := m.Lock(vgnames...)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			held++
+			running++
+			if running > max {
+				max = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			unlock()
+		}(i)
+	}
+	wg.Wait()
+	if held != 5 || max != 1 {
+		t.Fatalf("expected 5 exclusive holders, got %d with at most %d at once", held, max)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "locks", lockFilePrefix+"vg1")); err != nil {
+		t.Fatalf("expected a lock file: %v", err)
+	}
+}
+
+func TestLockContentionRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "marker")
+	script := filepath.Join(dir, "lvm")
+	contents := "#!/bin/sh\nif ! test -e " + marker + "; then touch " + marker + "; echo \"  Can't get lock for vg\" >&2; exit 5; fi\n"
+	if err = ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{LVMPath: script}
+	err = c.ActivateVolumeGroup("vg")
+	if !IsLockContention(err) {
+		t.Fatalf("expected a lock contention error, got %v", err)
+	}
+	os.Remove(marker)
+
+	c.Retry = &RetryPolicy{Backoff: Backoff{Attempts: 3, Initial: time.Millisecond}, RetryableChanges: []ErrorClass{ErrorLockContention}}
+	c.Locks = NewLockManager("")
+	if err = c.ActivateVolumeGroup("vg"); err != nil {
+		t.Fatalf("expected lock contention to be retried, got %v", err)
+	}
+}
+
+func TestGlobalLockExcludesVolumeGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "lvm")
+	contents := "#!/bin/sh\necho start $1 >> " + log + "\nsleep 0.05\necho end $1 >> " + log + "\n"
+	if err = ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{LVMPath: script, Locks: NewLockManager(filepath.Join(dir, "locks"))}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := c.CreatePhysicalVolume("/dev/loop0"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.CreateVolumeGroup("vg", "/dev/loop0"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	output, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("expected 4 commands to run, got %q", lines)
+	}
+	for i := 0; i < len(lines); i += 2 {
+		command := strings.TrimPrefix(lines[i], "start ")
+		if lines[i] == command || lines[i+1] != "end "+command {
+			t.Fatalf("expected pvcreate and vgcreate to not overlap, got %q", lines)
+		}
+	}
+
+	// Changes to different volume groups share the global lock.
+	m := c.Locks
+	unlock1, err := m.Lock("vg1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock2, err := m.Lock("vg2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock2()
+	unlock1()
+}
@@ -0,0 +1,142 @@
+package lvm
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// LosetupPath is the path to the "losetup" command.
+	LosetupPath string
+)
+
+func init() {
+	if p, err := exec.LookPath("losetup"); err == nil {
+		LosetupPath = p
+	}
+}
+
+// GetLoopbackDevices is a wrapper around DefaultClient.GetLoopbackDevices.
+func GetLoopbackDevices() (Report, error) {
+	return DefaultClient.GetLoopbackDevices()
+}
+
+// GetLoopbackDevices lists the configured loopback devices and the files that
+// they're attached to.  Only the Name and File fields of each ReportLoopback
+// are filled in, since the other fields are reported in different formats by
+// different versions of losetup.
+func (c *Client) GetLoopbackDevices() (Report, error) {
+	output, err := c.run(Command{Path: LosetupPath, Args: []string{"--list", "--json", "--output", "NAME,BACK-FILE"}})
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error running \"losetup --list\"")
+	}
+	report := Report{}
+	if strings.TrimSpace(output) == "" {
+		return report, nil
+	}
+	if err = json.Unmarshal([]byte(output), &report); err != nil {
+		return Report{}, errors.Wrapf(err, "error decoding output from \"losetup --list\"")
+	}
+	return report, nil
+}
+
+// AttachLoopback is a wrapper around DefaultClient.AttachLoopback.
+func AttachLoopback(file string) (string, error) {
+	return DefaultClient.AttachLoopback(file)
+}
+
+// dryRunLoopDevice is the device which AttachLoopback reports in a dry run,
+// in which no device is attached.
+const dryRunLoopDevice = "/dev/loopN"
+
+// AttachLoopback attaches a file to the first free loopback device, and
+// returns the device's pathname.  Finding the device and attaching the file
+// happen in one command, so that callers can't race for the same device.  In
+// a dry run, the command is recorded and a placeholder device is returned.
+func (c *Client) AttachLoopback(file string) (string, error) {
+	output, err := c.mutateCommand(Command{Path: LosetupPath, Args: []string{"--find", "--show", file}})
+	if err != nil {
+		return "", errors.Wrapf(err, "error running \"losetup\" to attach %q", file)
+	}
+	if c.dryRun() {
+		return dryRunLoopDevice, nil
+	}
+	device := strings.TrimSpace(output)
+	if device == "" {
+		return "", errors.Errorf("\"losetup\" didn't report which device %q was attached to", file)
+	}
+	return device, nil
+}
+
+// DetachLoopback is a wrapper around DefaultClient.DetachLoopback.
+func DetachLoopback(device string) error {
+	return DefaultClient.DetachLoopback(device)
+}
+
+// DetachLoopback detaches a loopback device from its file.
+func (c *Client) DetachLoopback(device string) error {
+	_, err := c.mutateCommand(Command{Path: LosetupPath, Args: []string{"--detach", device}})
+	if err != nil {
+		return errors.Wrapf(err, "error running \"losetup --detach\" for %q", device)
+	}
+	return nil
+}
+
+// BootstrapLoopback is a wrapper around DefaultClient.BootstrapLoopback.
+func BootstrapLoopback(file string, size int64, vgname, poolname string) (string, error) {
+	return DefaultClient.BootstrapLoopback(file, size, vgname, poolname)
+}
+
+// BootstrapLoopback creates a sparse file of the specified size, attaches it
+// to a loopback device, and creates a volume group on it, along with a thin
+// pool using most of its space if poolname is not empty.  It returns the
+// loopback device's pathname.  If any step fails, the device is detached and
+// the file is removed.  In a dry run, the file is not created.  This is mainly
+// useful for testing.
+func (c *Client) BootstrapLoopback(file string, size int64, vgname, poolname string) (string, error) {
+	dryRun := c.dryRun()
+	if !dryRun {
+		f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return "", errors.Wrapf(err, "error creating %q", file)
+		}
+		err = f.Truncate(size)
+		f.Close()
+		if err != nil {
+			os.Remove(file)
+			return "", errors.Wrapf(err, "error resizing %q", file)
+		}
+	}
+	cleanup := func(device string) {
+		if device != "" {
+			if err := c.DetachLoopback(device); err != nil {
+				logrus.Errorf("error detaching %q: %v", device, err)
+			}
+		}
+		if !dryRun {
+			if err := os.Remove(file); err != nil {
+				logrus.Errorf("error removing %q: %v", file, err)
+			}
+		}
+	}
+	device, err := c.AttachLoopback(file)
+	if err != nil {
+		cleanup("")
+		return "", err
+	}
+	if err = c.CreatePhysicalVolume(device); err == nil {
+		if err = c.CreateVolumeGroup(vgname, device); err == nil && poolname != "" {
+			err = c.CreateThinPool(vgname, poolname, Size{Percent: 90, Of: "FREE"})
+		}
+	}
+	if err != nil {
+		cleanup(device)
+		return "", err
+	}
+	return device, nil
+}
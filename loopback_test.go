@@ -0,0 +1,82 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetLoopbackDevices(t *testing.T) {
+	script, runs := fakeLVM(t, `{"loopdevices": [{"name": "/dev/loop0", "back-file": "/var/lib/disk.img (deleted)"}]}`)
+	saved := LosetupPath
+	LosetupPath = script
+	defer func() { LosetupPath = saved }()
+
+	report, err := (&Client{}).GetLoopbackDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Loopback) != 1 || report.Loopback[0].Name != "/dev/loop0" || report.Loopback[0].File != "/var/lib/disk.img (deleted)" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if args := runs(); len(args) != 1 || args[0] != "--list --json --output NAME,BACK-FILE" {
+		t.Fatalf("unexpected arguments %q", args)
+	}
+}
+
+func TestBootstrapLoopback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "disk.img")
+	script, runs := fakeLVM(t, "/dev/loop7\n")
+	saved := LosetupPath
+	LosetupPath = script
+	defer func() { LosetupPath = saved }()
+
+	// A dry run records the commands, without creating the file.
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+	device, err := c.BootstrapLoopback(file, 1<<20, "vg", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device != dryRunLoopDevice {
+		t.Fatalf("expected device %q, got %q", dryRunLoopDevice, device)
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to not be created in a dry run, got %v", err)
+	}
+	commands := []string{}
+	for _, cmd := range recorder.Commands() {
+		commands = append(commands, strings.TrimPrefix(cmd.String(), script+" "))
+	}
+	expected := []string{"--find --show " + file, "pvcreate /dev/loopN", "vgcreate vg /dev/loopN", "lvcreate --type thin-pool --extents 90%FREE --name pool vg"}
+	if strings.Join(commands, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected commands %q, got %q", expected, commands)
+	}
+
+	// A failure detaches the device and removes the file.
+	c = &Client{LVMPath: script, Executor: failingExecutor{Executor: DefaultExecutor, fail: "vgcreate"}}
+	if _, err = c.BootstrapLoopback(file, 1<<20, "vg", ""); err == nil {
+		t.Fatal("expected an error when the volume group can't be created")
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to be removed, got %v", err)
+	}
+	expected = []string{"--find --show " + file, "pvcreate /dev/loop7", "--detach /dev/loop7"}
+	if args := runs(); strings.Join(args, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected commands %q, got %q", expected, args)
+	}
+
+	if err = ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.BootstrapLoopback(file, 1<<20, "vg", ""); err == nil {
+		t.Fatal("expected an error reusing an existing file")
+	}
+}
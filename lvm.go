@@ -7,6 +7,7 @@ import (
 	"os/exec"
     "strings"
 
+	"github.com/haircommander/lvm-go/metadata"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -22,17 +23,6 @@ func init() {
 	}
 }
 
-func runWithoutOutput(cmdPath string, args ...string) error {
-	logrus.Debugf("running %v", append([]string{cmdPath}, args...))
-	cmd := exec.Command(cmdPath, args...)
-	stderr := bytes.Buffer{}
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return errors.Errorf("%s", stderr.String())
-	}
-	return nil
-}
-
 func runWithOutput(cmdPath string, args ...string) (string, error) {
 	logrus.Debugf("running %v", append([]string{cmdPath}, args...))
 	cmd := exec.Command(cmdPath, args...)
@@ -41,24 +31,33 @@ func runWithOutput(cmdPath string, args ...string) (string, error) {
 	stderr := bytes.Buffer{}
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return stdout.String(), errors.Errorf("%s", stderr.String())
+		cmdErr := &CommandError{Path: cmdPath, Args: args, Stderr: stderr.String(), ExitCode: -1, Err: err}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+		return stdout.String(), errors.WithStack(cmdErr)
 	}
 	return stdout.String(), nil
 }
 
+// GetPhysicalVolumes is a wrapper around DefaultClient.GetPhysicalVolumes.
+func GetPhysicalVolumes(pvname string) (Report, error) {
+	return DefaultClient.GetPhysicalVolumes(pvname)
+}
+
 // GetPhysicalVolumes returns information about known physical volumes or a
 // specific physical volume.
-func GetPhysicalVolumes(pvname string) (Report, error) {
+func (c *Client) GetPhysicalVolumes(pvname string) (Report, error) {
 	report := Report{}
 	b := []byte{}
 	if pvname != "" {
-		raw, err := runWithOutput(LVMPath, "pvs", "--reportformat", "json", "--units", "b", "--nosuffix", pvname)
+		raw, err := c.report("pvs", "--reportformat", "json", "--units", "b", "--nosuffix", pvname)
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvs pvs\" for %q", pvname)
 		}
 		b = []byte(raw)
 	} else {
-		raw, err := runWithOutput(LVMPath, "pvs", "--reportformat", "json", "--units", "b", "--nosuffix")
+		raw, err := c.report("pvs", "--reportformat", "json", "--units", "b", "--nosuffix")
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvs pvs\"")
 		}
@@ -71,19 +70,24 @@ func GetPhysicalVolumes(pvname string) (Report, error) {
 	return report, nil
 }
 
+// GetVolumeGroups is a wrapper around DefaultClient.GetVolumeGroups.
+func GetVolumeGroups(vgname string) (Report, error) {
+	return DefaultClient.GetVolumeGroups(vgname)
+}
+
 // GetVolumeGroups returns information about the known volume groups, or about
 // a specific volume group.
-func GetVolumeGroups(vgname string) (Report, error) {
+func (c *Client) GetVolumeGroups(vgname string) (Report, error) {
 	report := Report{}
 	b := []byte{}
 	if vgname != "" {
-		raw, err := runWithOutput(LVMPath, "vgs", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
+		raw, err := c.report("vgs", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvs vgs\" for %q", vgname)
 		}
 		b = []byte(raw)
 	} else {
-		raw, err := runWithOutput(LVMPath, "vgs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix")
+		raw, err := c.report("vgs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix")
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvs vgs\"")
 		}
@@ -96,19 +100,34 @@ func GetVolumeGroups(vgname string) (Report, error) {
 	return report, nil
 }
 
-// getVolumeGroupsFull returns detailed information about all known volume
-// groups, or about one specific volume group.
-func getVolumeGroupsFull(vgname string) (ReportFull, error) {
+// GetFullReport is a wrapper around DefaultClient.GetFullReport.
+func GetFullReport(vgname string) (ReportFull, error) {
+	return DefaultClient.GetFullReport(vgname)
+}
+
+// GetFullReport returns detailed information about all known volume groups,
+// or about one specific volume group.
+func (c *Client) GetFullReport(vgname string) (ReportFull, error) {
+	return c.getFullReport(vgname, false)
+}
+
+// getFullReport reads a full report, including internal logical volumes, like
+// the data and metadata volumes of thin pools, if all is true.
+func (c *Client) getFullReport(vgname string, all bool) (ReportFull, error) {
 	report := ReportFull{}
+	args := []string{"fullreport", "--reportformat", "json", "--units", "b", "--nosuffix"}
+	if all {
+		args = append(args, "--all")
+	}
 	b := []byte{}
 	if vgname != "" {
-		raw, err := runWithOutput(LVMPath, "fullreport", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
+		raw, err := c.report(append(args, vgname)...)
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvm fullreport\" for %q", vgname)
 		}
 		b = []byte(raw)
 	} else {
-		raw, err := runWithOutput(LVMPath, "fullreport", "--reportformat", "json", "--units", "b", "--nosuffix")
+		raw, err := c.report(args...)
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvm fullreport\"")
 		}
@@ -121,27 +140,32 @@ func getVolumeGroupsFull(vgname string) (ReportFull, error) {
 	return report, nil
 }
 
+// GetLogicalVolumes is a wrapper around DefaultClient.GetLogicalVolumes.
+func GetLogicalVolumes(vgname, volume string) (Report, error) {
+	return DefaultClient.GetLogicalVolumes(vgname, volume)
+}
+
 // GetLogicalVolumes returns information about all known logical volumes, about
 // the volumes in a specified volume group, or about a specific volume.
-func GetLogicalVolumes(vgname, volume string) (Report, error) {
+func (c *Client) GetLogicalVolumes(vgname, volume string) (Report, error) {
 	report := Report{}
 	b := []byte{}
 	if vgname != "" {
 		if volume != "" {
-			raw, err := runWithOutput(LVMPath, "lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix", vgname+"/"+volume)
+			raw, err := c.report("lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix", vgname+"/"+volume)
 			if err != nil {
 				return report, errors.Wrapf(err, "error running \"lvm lvs\" for %q", vgname+"/"+volume)
 			}
 			b = []byte(raw)
 		} else {
-			raw, err := runWithOutput(LVMPath, "lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
+			raw, err := c.report("lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
 			if err != nil {
 				return report, errors.Wrapf(err, "error running \"lvm lvs\" for %q", vgname)
 			}
 			b = []byte(raw)
 		}
 	} else {
-		raw, err := runWithOutput(LVMPath, "lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix")
+		raw, err := c.report("lvs", "--all", "--reportformat", "json", "--units", "b", "--nosuffix")
 		if err != nil {
 			return report, errors.Wrapf(err, "error running \"lvm lvs\"")
 		}
@@ -154,76 +178,76 @@ func GetLogicalVolumes(vgname, volume string) (Report, error) {
 	return report, nil
 }
 
-// physicalVolumeIsPresent checks if a physical volume with the specified name
-// exists.  Force a rescan of that device for physical volume header data, for
-// cases where we've just attached it.
+// PhysicalVolumeIsPresent is a wrapper around DefaultClient.PhysicalVolumeIsPresent.
 func PhysicalVolumeIsPresent(pvname string) bool {
-	scanned, err := runWithOutput(LVMPath, "pvscan", "--cache", pvname)
+	return DefaultClient.PhysicalVolumeIsPresent(pvname)
+}
+
+// PhysicalVolumeIsPresent checks if a physical volume with the specified name
+// exists, by reading its label directly with PhysicalVolumeHasLabel.  The
+// check is label-only: the PV's metadata isn't validated the way pvck would,
+// and lvm's device cache is no longer refreshed with "pvscan --cache", so
+// callers which need lvm to notice a just-attached device on a system with
+// lvmetad must rescan it themselves.  Since no command is run, the Client's
+// Executor, dry run, audit, and lock settings don't apply.
+func (c *Client) PhysicalVolumeIsPresent(pvname string) bool {
+	return PhysicalVolumeHasLabel(pvname)
+}
+
+// PhysicalVolumeHasLabel checks if the specified device or image file carries
+// an LVM2 physical volume label, by reading it directly instead of asking lvm.
+func PhysicalVolumeHasLabel(pvname string) bool {
+	f, err := os.Open(pvname)
 	if err != nil {
-		logrus.Debugf("lvm pvscan failed: %q", scanned)
+		logrus.Debugf("error opening %q: %v", pvname, err)
 		return false
 	}
-	checked, err := runWithOutput(LVMPath, "pvck", pvname)
-	if err != nil {
-		logrus.Debugf("lvm pvck failed: %q", checked)
+	defer f.Close()
+	if _, err = metadata.ReadLabel(f); err != nil {
+		logrus.Debugf("error reading label from %q: %v", pvname, err)
 		return false
 	}
 	return true
 }
 
-// TODO FIXME maybe move this?
-// volumeNameForID converts an ID into a reasonable volume name.
-func VolumeNameForID(ID string) string {
-    return "layer." + ID
+// VolumePathForID is a wrapper around DefaultClient.VolumePathForID.
+func VolumePathForID(vgname, id string) (string, error) {
+	return DefaultClient.VolumePathForID(vgname, id)
 }
 
-// TODO FIXME maybe move this?
-// volumePathForID determines the device pathname for a volume with the
+// VolumePathForID determines the device pathname for a volume with the
 // specified ID in a particular volume group, or across all volume groups.
-func VolumePathForID(vgname, id string) (string, error) {
-	lvname := VolumeNameForID(id)
-	report, err := getVolumeGroupsFull(vgname)
+func (c *Client) VolumePathForID(vgname, id string) (string, error) {
+	lvname, _, err := c.namer().VolumeName(id)
+	if err != nil {
+		return "", err
+	}
+	report, err := c.GetFullReport(vgname)
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
 	for _, entry := range report.Reports {
-		if vgname != "" {
-			foundVG := false
-			for _, vg := range entry.VGs {
-				if vg.Name == vgname {
-					foundVG = true
-					break
-				}
-			}
-			if !foundVG {
-				continue
-			}
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
 		}
 		for _, lv := range entry.LVs {
 			if lv.Name == lvname {
-				if lv.DMPath != "" {
-					_, err = os.Stat(lv.DMPath)
-					if err == nil {
-						return lv.DMPath, nil
-					}
-				}
-				if lv.Path != "" {
-					_, err = os.Stat(lv.Path)
-					if err == nil {
-						return lv.Path, nil
-					}
-				}
-				return "", errors.Errorf("found LV %q, but no active path for it", vgname+"/"+lv.Name)
+				return activeVolumePath(vgname, lv)
 			}
 		}
 	}
 	return "", errors.Errorf("no LV named %q found", vgname+"/"+lvname)
 }
 
+// ReadVolumeGroupForPhysicalVolume is a wrapper around DefaultClient.ReadVolumeGroupForPhysicalVolume.
+func ReadVolumeGroupForPhysicalVolume(pvname string) (string, error) {
+	return DefaultClient.ReadVolumeGroupForPhysicalVolume(pvname)
+}
+
 // ReadVolumeGroupForPhysicalVolume will determine the name of the volume group
 // to which the specified physical volume belongs.
-func ReadVolumeGroupForPhysicalVolume(pvname string) (string, error) {
-	report, err := GetPhysicalVolumes(pvname)
+func (c *Client) ReadVolumeGroupForPhysicalVolume(pvname string) (string, error) {
+	report, err := c.GetPhysicalVolumes(pvname)
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
@@ -237,14 +261,19 @@ func ReadVolumeGroupForPhysicalVolume(pvname string) (string, error) {
 	return "", errors.Errorf("no PV named %q found", pvname)
 }
 
-// VolumeGroupIsPresent checks if a volume group with the specified name exists.
+// VolumeGroupIsPresent is a wrapper around DefaultClient.VolumeGroupIsPresent.
 func VolumeGroupIsPresent(vgname string) bool {
-	scanned, err := runWithOutput(LVMPath, "vgscan", "--cache")
+	return DefaultClient.VolumeGroupIsPresent(vgname)
+}
+
+// VolumeGroupIsPresent checks if a volume group with the specified name exists.
+func (c *Client) VolumeGroupIsPresent(vgname string) bool {
+	scanned, err := c.query("vgscan", "--cache")
 	if err != nil {
 		logrus.Debugf("lvm vgscan failed for %q: %q", vgname, scanned)
 		return false
 	}
-	scanned, err = runWithOutput(LVMPath, "vgs", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
+	scanned, err = c.query("vgs", "--reportformat", "json", "--units", "b", "--nosuffix", vgname)
 	if err != nil {
 		logrus.Debugf("lvm vgs failed for %q: %q", vgname, scanned)
 		return false
@@ -252,9 +281,14 @@ func VolumeGroupIsPresent(vgname string) bool {
 	return true
 }
 
-// GetLogicalVolume returns information about the specified logical volume.
+// GetLogicalVolume is a wrapper around DefaultClient.GetLogicalVolume.
 func GetLogicalVolume(vgname, volume string) (ReportLV, error) {
-	report, err := GetLogicalVolumes(vgname, volume)
+	return DefaultClient.GetLogicalVolume(vgname, volume)
+}
+
+// GetLogicalVolume returns information about the specified logical volume.
+func (c *Client) GetLogicalVolume(vgname, volume string) (ReportLV, error) {
+	report, err := c.GetLogicalVolumes(vgname, volume)
 	if err != nil {
 		return ReportLV{}, errors.WithStack(err)
 	}
@@ -268,10 +302,15 @@ func GetLogicalVolume(vgname, volume string) (ReportLV, error) {
 	return ReportLV{}, errors.Errorf("no LV named %q found", volume)
 }
 
+// LogicalVolumeIsPresent is a wrapper around DefaultClient.LogicalVolumeIsPresent.
+func LogicalVolumeIsPresent(vgname, volume string) bool {
+	return DefaultClient.LogicalVolumeIsPresent(vgname, volume)
+}
+
 // LogicalVolumeIsPresent checks if a logical volume with the specified name in
 // the specified volume group exists.
-func LogicalVolumeIsPresent(vgname, volume string) bool {
-	scanned, err := runWithOutput(LVMPath, "lvscan", "--cache", vgname+"/"+volume)
+func (c *Client) LogicalVolumeIsPresent(vgname, volume string) bool {
+	scanned, err := c.query("lvscan", "--cache", vgname+"/"+volume)
 	if err != nil {
 		logrus.Debugf("lvm lvscan failed: %q", scanned)
 		return false
@@ -279,19 +318,29 @@ func LogicalVolumeIsPresent(vgname, volume string) bool {
 	return true
 }
 
-// CreatePhysicalVolume formats a specified device as a physical volume.
+// CreatePhysicalVolume is a wrapper around DefaultClient.CreatePhysicalVolume.
 func CreatePhysicalVolume(device string) error {
-	err := runWithoutOutput(LVMPath, "pvcreate", device)
+	return DefaultClient.CreatePhysicalVolume(device)
+}
+
+// CreatePhysicalVolume formats a specified device as a physical volume.
+func (c *Client) CreatePhysicalVolume(device string) error {
+	_, err := c.mutate(nil, "pvcreate", device)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm pvcreate\" for %q", device)
 	}
 	return nil
 }
 
+// ResizePhysicalVolume is a wrapper around DefaultClient.ResizePhysicalVolume.
+func ResizePhysicalVolume(device string) error {
+	return DefaultClient.ResizePhysicalVolume(device)
+}
+
 // ResizePhysicalVolume tells the kernel that the loopback device may be larger
 // now, so the volume group that its in will care about that.
-func ResizePhysicalVolume(device string) error {
-	output, err := runWithOutput(LVMPath, "pvresize", device)
+func (c *Client) ResizePhysicalVolume(device string) error {
+	output, err := c.mutate(nil, "pvresize", device)
 	output = strings.TrimRight(output, "\r\n\t ")
 	if err != nil {
 		return errors.Wrapf(err, "error checking if device %q has been resized: %q", device, output)
@@ -299,73 +348,246 @@ func ResizePhysicalVolume(device string) error {
 	return nil
 }
 
-// CreateVolumeGroup formats a specified device as a physical volume.
+// CreateVolumeGroup is a wrapper around DefaultClient.CreateVolumeGroup.
 func CreateVolumeGroup(vgname string, device ...string) error {
-	err := runWithoutOutput(LVMPath, append([]string{"vgcreate", vgname}, device...)...)
+	return DefaultClient.CreateVolumeGroup(vgname, device...)
+}
+
+// CreateVolumeGroup formats a specified device as a physical volume.
+func (c *Client) CreateVolumeGroup(vgname string, device ...string) error {
+	_, err := c.mutate([]string{vgname}, append([]string{"vgcreate", vgname}, device...)...)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm vgcreate\" for %v", device)
 	}
 	return nil
 }
 
+// ActivateVolumeGroup is a wrapper around DefaultClient.ActivateVolumeGroup.
+func ActivateVolumeGroup(vgname string) error {
+	return DefaultClient.ActivateVolumeGroup(vgname)
+}
+
 // ActivateVolumeGroup activates the specified volume group, making all of its
 // logical volumes visible.
-func ActivateVolumeGroup(vgname string) error {
-	err := runWithoutOutput(LVMPath, "vgchange", "--activate", "y", "--ignoreactivationskip", vgname)
+func (c *Client) ActivateVolumeGroup(vgname string) error {
+	_, err := c.mutate([]string{vgname}, "vgchange", "--activate", "y", "--ignoreactivationskip", vgname)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm vgchange --activate y\" for %q", vgname)
 	}
 	return nil
 }
 
+// DeactivateVolumeGroup is a wrapper around DefaultClient.DeactivateVolumeGroup.
+func DeactivateVolumeGroup(vgname string) error {
+	return DefaultClient.DeactivateVolumeGroup(vgname)
+}
+
 // DeactivateVolumeGroup deactivates the specified volume group, making all of
 // its logical volumes invisible.
-func DeactivateVolumeGroup(vgname string) error {
-	err := runWithoutOutput(LVMPath, "vgchange", "--activate", "n", vgname)
+func (c *Client) DeactivateVolumeGroup(vgname string) error {
+	_, err := c.mutate([]string{vgname}, "vgchange", "--activate", "n", vgname)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm vgchange --activate n\" for %q", vgname)
 	}
 	return nil
 }
 
+// ActivateLogicalVolume is a wrapper around DefaultClient.ActivateLogicalVolume.
+func ActivateLogicalVolume(vgname, volume string) error {
+	return DefaultClient.ActivateLogicalVolume(vgname, volume)
+}
+
 // ActivateLogicalVolume activates a single logical volume in the specified
 // volume group, making it visible.
-func ActivateLogicalVolume(vgname, volume string) error {
-	err := runWithoutOutput(LVMPath, "lvchange", "--activate", "y", "--ignoreactivationskip", vgname+"/"+volume)
+func (c *Client) ActivateLogicalVolume(vgname, volume string) error {
+	_, err := c.mutate([]string{vgname}, "lvchange", "--activate", "y", "--ignoreactivationskip", vgname+"/"+volume)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm lvchange --activate y\" for %q", vgname+"/"+volume)
 	}
 	return nil
 }
 
+// DeactivateLogicalVolume is a wrapper around DefaultClient.DeactivateLogicalVolume.
+func DeactivateLogicalVolume(vgname, volume string) error {
+	return DefaultClient.DeactivateLogicalVolume(vgname, volume)
+}
+
 // DeactivateLogicalVolume deactivates a single logical volume in the specified
 // volume group, making it invisible.
-func DeactivateLogicalVolume(vgname, volume string) error {
-	err := runWithoutOutput(LVMPath, "lvchange", "--activate", "n", vgname+"/"+volume)
+func (c *Client) DeactivateLogicalVolume(vgname, volume string) error {
+	_, err := c.mutate([]string{vgname}, "lvchange", "--activate", "n", vgname+"/"+volume)
 	if err != nil {
 		return errors.Wrapf(err, "error running \"lvm lvchange --activate n\" for %q", vgname+"/"+volume)
 	}
 	return nil
 }
 
-// read information about the active thin pool
+// CreateThinPool is a wrapper around DefaultClient.CreateThinPool.
+func CreateThinPool(vgname, poolname string, size Size) error {
+	return DefaultClient.CreateThinPool(vgname, poolname, size)
+}
+
+// CreateThinPool creates a thin pool in a volume group.  The size can be a
+// percentage of the volume group's free space or total size, for example
+// "90%FREE".
+func (c *Client) CreateThinPool(vgname, poolname string, size Size) error {
+	args := append([]string{"lvcreate", "--type", "thin-pool"}, size.Args()...)
+	_, err := c.mutate([]string{vgname}, append(args, "--name", poolname, vgname)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvcreate --type thin-pool\" for %q", vgname+"/"+poolname)
+	}
+	return nil
+}
+
+// CreateThinVolume is a wrapper around DefaultClient.CreateThinVolume.
+func CreateThinVolume(vgname, poolname, volume string, size Size, tag ...string) error {
+	return DefaultClient.CreateThinVolume(vgname, poolname, volume, size, tag...)
+}
+
+// CreateThinVolume creates a thin logical volume of the specified size in a
+// thin pool, with the specified tags.  Since the volume's size is not taken
+// from the pool, it can't be a percentage.
+func (c *Client) CreateThinVolume(vgname, poolname, volume string, size Size, tag ...string) error {
+	if size.IsPercentage() {
+		return errors.Errorf("the size of thin volume %q can't be a percentage", vgname+"/"+volume)
+	}
+	args := []string{"lvcreate", "--thin", "--virtualsize", size.String() + "b", "--name", volume}
+	for _, t := range tag {
+		args = append(args, "--addtag", t)
+	}
+	_, err := c.mutate([]string{vgname}, append(args, vgname+"/"+poolname)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvcreate --thin\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+// CreateThinSnapshot is a wrapper around DefaultClient.CreateThinSnapshot.
+func CreateThinSnapshot(vgname, origin, volume string, tag ...string) error {
+	return DefaultClient.CreateThinSnapshot(vgname, origin, volume, tag...)
+}
+
+// CreateThinSnapshot creates a thin snapshot of a thin logical volume, with
+// the specified tags.  Unlike lvm's default for thin snapshots, the snapshot
+// is not marked to be skipped during activation.
+func (c *Client) CreateThinSnapshot(vgname, origin, volume string, tag ...string) error {
+	args := []string{"lvcreate", "--snapshot", "--setactivationskip", "n", "--name", volume}
+	for _, t := range tag {
+		args = append(args, "--addtag", t)
+	}
+	_, err := c.mutate([]string{vgname}, append(args, vgname+"/"+origin)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvcreate --snapshot\" of %q", vgname+"/"+origin)
+	}
+	return nil
+}
+
+// ExtendLogicalVolume is a wrapper around DefaultClient.ExtendLogicalVolume.
+func ExtendLogicalVolume(vgname, volume string, size Size) error {
+	return DefaultClient.ExtendLogicalVolume(vgname, volume, size)
+}
+
+// ExtendLogicalVolume grows a logical volume to the specified size.
+func (c *Client) ExtendLogicalVolume(vgname, volume string, size Size) error {
+	args := append([]string{"lvextend"}, size.Args()...)
+	_, err := c.mutate([]string{vgname}, append(args, vgname+"/"+volume)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvextend\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+// RemoveLogicalVolume is a wrapper around DefaultClient.RemoveLogicalVolume.
+func RemoveLogicalVolume(vgname, volume string) error {
+	return DefaultClient.RemoveLogicalVolume(vgname, volume)
+}
+
+// RemoveLogicalVolume removes a logical volume, deactivating it first if it is
+// active.
+func (c *Client) RemoveLogicalVolume(vgname, volume string) error {
+	_, err := c.mutate([]string{vgname}, "lvremove", "--force", vgname+"/"+volume)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm lvremove\" for %q", vgname+"/"+volume)
+	}
+	return nil
+}
+
+// ExportVolumeGroup is a wrapper around DefaultClient.ExportVolumeGroup.
+func ExportVolumeGroup(vgname string) error {
+	return DefaultClient.ExportVolumeGroup(vgname)
+}
+
+// ExportVolumeGroup marks the specified volume group as exported, so that it
+// can be moved to another system.  Its logical volumes must be inactive.
+func (c *Client) ExportVolumeGroup(vgname string) error {
+	_, err := c.mutate([]string{vgname}, "vgexport", vgname)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgexport\" for %q", vgname)
+	}
+	return nil
+}
+
+// ImportVolumeGroup is a wrapper around DefaultClient.ImportVolumeGroup.
+func ImportVolumeGroup(vgname string) error {
+	return DefaultClient.ImportVolumeGroup(vgname)
+}
+
+// ImportVolumeGroup makes an exported volume group known to this system again.
+func (c *Client) ImportVolumeGroup(vgname string) error {
+	_, err := c.mutate([]string{vgname}, "vgimport", vgname)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgimport\" for %q", vgname)
+	}
+	return nil
+}
+
+// ImportClonedVolumeGroup is a wrapper around DefaultClient.ImportClonedVolumeGroup.
+func ImportClonedVolumeGroup(vgname string, device ...string) error {
+	return DefaultClient.ImportClonedVolumeGroup(vgname, device...)
+}
+
+// ImportClonedVolumeGroup imports a volume group from devices which are copies
+// of the physical volumes of another volume group, giving the copies new UUIDs
+// and the volume group the specified new name so that they don't conflict with
+// the originals.  A volume group that was exported is imported, too.
+func (c *Client) ImportClonedVolumeGroup(vgname string, device ...string) error {
+	_, err := c.mutate([]string{vgname}, append([]string{"vgimportclone", "--basevgname", vgname, "--import"}, device...)...)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgimportclone\" for %v", device)
+	}
+	return nil
+}
+
+// SetVolumeGroupSystemID is a wrapper around DefaultClient.SetVolumeGroupSystemID.
+func SetVolumeGroupSystemID(vgname, systemID string) error {
+	return DefaultClient.SetVolumeGroupSystemID(vgname, systemID)
+}
+
+// SetVolumeGroupSystemID sets the system ID of the specified volume group,
+// which controls which host is allowed to use it.  An empty systemID clears
+// it, allowing any host to use the volume group.
+func (c *Client) SetVolumeGroupSystemID(vgname, systemID string) error {
+	_, err := c.mutate([]string{vgname}, "vgchange", "--systemid", systemID, vgname)
+	if err != nil {
+		return errors.Wrapf(err, "error running \"lvm vgchange --systemid\" for %q", vgname)
+	}
+	return nil
+}
+
+// ReadPoolInfo is a wrapper around DefaultClient.ReadPoolInfo.
 func ReadPoolInfo(vgname, poolname string) (LvmPoolHistory, error) {
-	report, err := getVolumeGroupsFull(vgname)
+	return DefaultClient.ReadPoolInfo(vgname, poolname)
+}
+
+// read information about the active thin pool
+func (c *Client) ReadPoolInfo(vgname, poolname string) (LvmPoolHistory, error) {
+	report, err := c.GetFullReport(vgname)
 	if err != nil {
 		return LvmPoolHistory{}, errors.Wrapf(err, "error reading information about volume group %q", vgname)
 	}
 	for _, entry := range report.Reports {
-		if vgname != "" {
-			foundVG := false
-			for _, vg := range entry.VGs {
-				if vg.Name == vgname {
-					foundVG = true
-					break
-				}
-			}
-			if !foundVG {
-				continue
-			}
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
 		}
 		for _, lv := range entry.LVs {
 			if lv.Name != poolname {
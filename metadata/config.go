@@ -0,0 +1,396 @@
+// Package metadata reads and writes the text format which LVM uses for volume
+// group metadata, both in the metadata areas of physical volumes and in the
+// files written by "lvm vgcfgbackup", without running lvm.
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Section is a named block of settings in LVM's text format.  The top level of
+// a metadata file is a Section with an empty name.  Values are kept in the
+// order in which they were read, so that a Section can be written back out
+// without reordering its contents.
+type Section struct {
+	Name     string
+	Values   []KeyValue
+	Sections []*Section
+}
+
+// KeyValue is a single setting in a Section.  The Value is an int64, a string,
+// or a []interface{} holding a mix of int64 and string values.
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// Get returns the value of the named setting, if it is present.
+func (s *Section) Get(key string) (interface{}, bool) {
+	for _, kv := range s.Values {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Int returns the value of the named setting if it is present and an integer.
+func (s *Section) Int(key string) (int64, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return 0, false
+	}
+	i, ok := v.(int64)
+	return i, ok
+}
+
+// String returns the value of the named setting if it is present and a string.
+func (s *Section) String(key string) (string, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return "", false
+	}
+	str, ok := v.(string)
+	return str, ok
+}
+
+// Strings returns the value of the named setting if it is present and an array
+// of strings.
+func (s *Section) Strings(key string) ([]string, bool) {
+	v, ok := s.Get(key)
+	if !ok {
+		return nil, false
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, str)
+	}
+	return strs, true
+}
+
+// Set replaces the value of the named setting, or appends it if it is not
+// already present.
+func (s *Section) Set(key string, value interface{}) {
+	for i := range s.Values {
+		if s.Values[i].Key == key {
+			s.Values[i].Value = value
+			return
+		}
+	}
+	s.Values = append(s.Values, KeyValue{Key: key, Value: value})
+}
+
+// Delete removes the named setting, if it is present.
+func (s *Section) Delete(key string) {
+	for i := range s.Values {
+		if s.Values[i].Key == key {
+			s.Values = append(s.Values[:i], s.Values[i+1:]...)
+			return
+		}
+	}
+}
+
+// Section returns the named subsection, if it is present.
+func (s *Section) Section(name string) (*Section, bool) {
+	for _, sub := range s.Sections {
+		if sub.Name == name {
+			return sub, true
+		}
+	}
+	return nil, false
+}
+
+// SetSection replaces the subsection which has the same name as sub, or
+// appends sub if there is no such subsection.
+func (s *Section) SetSection(sub *Section) {
+	for i := range s.Sections {
+		if s.Sections[i].Name == sub.Name {
+			s.Sections[i] = sub
+			return
+		}
+	}
+	s.Sections = append(s.Sections, sub)
+}
+
+// Copy returns a copy of the section which shares nothing with it.
+func (s *Section) Copy() *Section {
+	c := &Section{Name: s.Name}
+	for _, kv := range s.Values {
+		if list, ok := kv.Value.([]interface{}); ok {
+			kv.Value = append([]interface{}{}, list...)
+		}
+		c.Values = append(c.Values, kv)
+	}
+	for _, sub := range s.Sections {
+		c.Sections = append(c.Sections, sub.Copy())
+	}
+	return c
+}
+
+// ParseConfig parses text in LVM's configuration and metadata format.
+func ParseConfig(data []byte) (*Section, error) {
+	p := parser{lexer: lexer{data: data, line: 1}}
+	root := &Section{}
+	if err := p.parseSection(root, true); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// WriteTo writes the contents of the section in LVM's text format.  The
+// section's own name is not written, so writing the top-level section produces
+// a complete metadata file.
+func (s *Section) WriteTo(w io.Writer) (int64, error) {
+	buf := bytes.Buffer{}
+	s.write(&buf, 0)
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the contents of the section in LVM's text format.
+func (s *Section) Bytes() []byte {
+	buf := bytes.Buffer{}
+	s.write(&buf, 0)
+	return buf.Bytes()
+}
+
+func (s *Section) write(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, kv := range s.Values {
+		fmt.Fprintf(buf, "%s%s = %s\n", indent, kv.Key, formatValue(kv.Value))
+	}
+	for _, sub := range s.Sections {
+		buf.WriteString("\n")
+		fmt.Fprintf(buf, "%s%s {\n", indent, sub.Name)
+		sub.write(buf, depth+1)
+		fmt.Fprintf(buf, "%s}\n", indent)
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return quoteString(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return quoteString(fmt.Sprintf("%v", value))
+}
+
+func quoteString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenInt
+	tokenPunct
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value int64
+	line  int
+}
+
+type lexer struct {
+	data []byte
+	pos  int
+	line int
+}
+
+func isIdentifierByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("_.+-/", c) != -1
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.IndexByte("={}[],", c) != -1:
+			l.pos++
+			return token{kind: tokenPunct, text: string(c), line: l.line}, nil
+		case c == '"':
+			return l.quoted()
+		case isIdentifierByte(c):
+			start := l.pos
+			for l.pos < len(l.data) && isIdentifierByte(l.data[l.pos]) {
+				l.pos++
+			}
+			text := string(l.data[start:l.pos])
+			if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return token{kind: tokenInt, text: text, value: i, line: l.line}, nil
+			}
+			return token{kind: tokenIdentifier, text: text, line: l.line}, nil
+		default:
+			return token{}, errors.Errorf("line %d: unexpected character %q", l.line, c)
+		}
+	}
+	return token{kind: tokenEOF, line: l.line}, nil
+}
+
+func (l *lexer) quoted() (token, error) {
+	line := l.line
+	l.pos++
+	s := []byte{}
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '"':
+			return token{kind: tokenString, text: string(s), line: line}, nil
+		case '\\':
+			if l.pos < len(l.data) {
+				c = l.data[l.pos]
+				l.pos++
+			}
+		case '\n':
+			l.line++
+		}
+		s = append(s, c)
+	}
+	return token{}, errors.Errorf("line %d: unterminated string", line)
+}
+
+type parser struct {
+	lexer  lexer
+	peeked *token
+}
+
+func (p *parser) next() (token, error) {
+	if p.peeked != nil {
+		t := *p.peeked
+		p.peeked = nil
+		return t, nil
+	}
+	return p.lexer.next()
+}
+
+func (p *parser) peek() (token, error) {
+	if p.peeked == nil {
+		t, err := p.lexer.next()
+		if err != nil {
+			return token{}, err
+		}
+		p.peeked = &t
+	}
+	return *p.peeked, nil
+}
+
+func (p *parser) parseSection(section *Section, top bool) error {
+	for {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.kind == tokenEOF && top:
+			return nil
+		case t.kind == tokenEOF:
+			return errors.Errorf("line %d: unexpected end of input in section %q", t.line, section.Name)
+		case t.kind == tokenPunct && t.text == "}" && !top:
+			return nil
+		case t.kind != tokenIdentifier && t.kind != tokenInt:
+			return errors.Errorf("line %d: expected a setting or section name, got %q", t.line, t.text)
+		}
+		op, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case op.kind == tokenPunct && op.text == "{":
+			sub := &Section{Name: t.text}
+			if err := p.parseSection(sub, false); err != nil {
+				return err
+			}
+			section.Sections = append(section.Sections, sub)
+		case op.kind == tokenPunct && op.text == "=":
+			value, err := p.parseValue()
+			if err != nil {
+				return errors.Wrapf(err, "error parsing value for %q", t.text)
+			}
+			section.Values = append(section.Values, KeyValue{Key: t.text, Value: value})
+		default:
+			return errors.Errorf("line %d: expected \"=\" or \"{\" after %q", op.line, t.text)
+		}
+	}
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.kind == tokenInt:
+		return t.value, nil
+	case t.kind == tokenString:
+		return t.text, nil
+	case t.kind == tokenPunct && t.text == "[":
+		list := []interface{}{}
+		for {
+			n, err := p.peek()
+			if err != nil {
+				return nil, err
+			}
+			if n.kind == tokenPunct && n.text == "]" {
+				p.next()
+				return list, nil
+			}
+			item, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := item.([]interface{}); ok {
+				return nil, errors.Errorf("line %d: nested arrays are not supported", n.line)
+			}
+			list = append(list, item)
+			n, err = p.next()
+			if err != nil {
+				return nil, err
+			}
+			if n.kind == tokenPunct && n.text == "]" {
+				return list, nil
+			}
+			if n.kind != tokenPunct || n.text != "," {
+				return nil, errors.Errorf("line %d: expected \",\" or \"]\" in array, got %q", n.line, n.text)
+			}
+		}
+	}
+	return nil, errors.Errorf("line %d: unexpected %q where a value was expected", t.line, t.text)
+}
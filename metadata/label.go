@@ -0,0 +1,245 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sectorSize             = 512
+	labelScanSectors       = 4
+	labelID                = "LABELONE"
+	labelType              = "LVM2 001"
+	mdaMagic               = " LVM2 x[5A%r0N*>"
+	mdaHeaderSize          = 512
+	initialCRC             = 0xf597a6cf
+	rawLocationFlagIgnored = 0x00000001
+)
+
+// DiskArea is a region of a physical volume, in bytes from the start of the
+// device.  A Size of zero for a data area means that it extends to the end of
+// the device.
+type DiskArea struct {
+	Offset uint64
+	Size   uint64
+}
+
+// Label is the information that is recorded in an LVM2 physical volume label
+// and the PV header which follows it.
+type Label struct {
+	// Sector is the sector in which the label was found.
+	Sector uint64
+	// UUID is the physical volume's UUID, formatted the way lvm displays it.
+	UUID            string
+	DeviceSize      uint64
+	DataAreas       []DiskArea
+	MetadataAreas   []DiskArea
+	BootloaderAreas []DiskArea
+}
+
+// PhysicalVolumeInfo is the information that can be read from a physical
+// volume without consulting lvm.
+type PhysicalVolumeInfo struct {
+	Label
+	// Metadata is the most recent copy of the volume group metadata text from
+	// the first usable metadata area, or empty if the physical volume is not
+	// part of a volume group.
+	Metadata []byte
+}
+
+// ReadLabel looks for an LVM2 label in the first sectors of the device or
+// image, and reads the PV header which follows it.
+func ReadLabel(r io.ReaderAt) (*Label, error) {
+	buf := make([]byte, sectorSize*labelScanSectors)
+	n, err := r.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "error reading label sectors")
+	}
+	buf = buf[:n]
+	for sector := 0; (sector+1)*sectorSize <= len(buf); sector++ {
+		s := buf[sector*sectorSize : (sector+1)*sectorSize]
+		if string(s[0:8]) != labelID {
+			continue
+		}
+		if binary.LittleEndian.Uint64(s[8:16]) != uint64(sector) {
+			continue
+		}
+		if crc := binary.LittleEndian.Uint32(s[16:20]); crc != checksum(s[20:]) {
+			return nil, errors.Errorf("label in sector %d has bad checksum", sector)
+		}
+		if string(s[24:32]) != labelType {
+			return nil, errors.Errorf("label in sector %d has unrecognized type %q", sector, s[24:32])
+		}
+		offset := binary.LittleEndian.Uint32(s[20:24])
+		if offset < 32 || offset >= sectorSize {
+			return nil, errors.Errorf("label in sector %d has invalid header offset %d", sector, offset)
+		}
+		label, err := parsePVHeader(s[offset:])
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing PV header in sector %d", sector)
+		}
+		label.Sector = uint64(sector)
+		return label, nil
+	}
+	return nil, errors.New("no LVM2 label found")
+}
+
+func parsePVHeader(b []byte) (*Label, error) {
+	if len(b) < 40 {
+		return nil, errors.New("PV header is truncated")
+	}
+	label := &Label{
+		UUID:       formatUUID(b[0:32]),
+		DeviceSize: binary.LittleEndian.Uint64(b[32:40]),
+	}
+	b = b[40:]
+	var err error
+	if label.DataAreas, b, err = parseAreaList(b); err != nil {
+		return nil, errors.Wrapf(err, "error reading data area list")
+	}
+	if label.MetadataAreas, b, err = parseAreaList(b); err != nil {
+		return nil, errors.Wrapf(err, "error reading metadata area list")
+	}
+	// Newer versions of lvm follow the lists with an extension header that
+	// lists bootloader areas.  Its absence is not an error.
+	if len(b) >= 8 && binary.LittleEndian.Uint32(b[0:4]) != 0 {
+		label.BootloaderAreas, _, _ = parseAreaList(b[8:])
+	}
+	return label, nil
+}
+
+// parseAreaList reads a list of disk areas which is terminated by an entry
+// with a zero offset.
+func parseAreaList(b []byte) ([]DiskArea, []byte, error) {
+	areas := []DiskArea{}
+	for {
+		if len(b) < 16 {
+			return nil, nil, errors.New("area list is not terminated")
+		}
+		area := DiskArea{
+			Offset: binary.LittleEndian.Uint64(b[0:8]),
+			Size:   binary.LittleEndian.Uint64(b[8:16]),
+		}
+		b = b[16:]
+		if area.Offset == 0 {
+			return areas, b, nil
+		}
+		areas = append(areas, area)
+	}
+}
+
+// ReadMetadataArea reads the most recent copy of the volume group metadata
+// text from the metadata area which starts at the specified location.  The
+// area is a circular buffer following a header, so the text may wrap around
+// to the start of the buffer.
+func ReadMetadataArea(r io.ReaderAt, area DiskArea) ([]byte, error) {
+	header := make([]byte, mdaHeaderSize)
+	if _, err := r.ReadAt(header, int64(area.Offset)); err != nil {
+		return nil, errors.Wrapf(err, "error reading metadata area header at %d", area.Offset)
+	}
+	if crc := binary.LittleEndian.Uint32(header[0:4]); crc != checksum(header[4:]) {
+		return nil, errors.Errorf("metadata area header at %d has bad checksum", area.Offset)
+	}
+	if string(header[4:20]) != mdaMagic {
+		return nil, errors.Errorf("metadata area header at %d has bad magic", area.Offset)
+	}
+	if start := binary.LittleEndian.Uint64(header[24:32]); start != area.Offset {
+		return nil, errors.Errorf("metadata area header at %d claims to be at %d", area.Offset, start)
+	}
+	size := binary.LittleEndian.Uint64(header[32:40])
+	// The first raw location describes the current metadata.
+	loc := header[40:64]
+	offset := binary.LittleEndian.Uint64(loc[0:8])
+	length := binary.LittleEndian.Uint64(loc[8:16])
+	crc := binary.LittleEndian.Uint32(loc[16:20])
+	flags := binary.LittleEndian.Uint32(loc[20:24])
+	if offset == 0 || length == 0 || flags&rawLocationFlagIgnored != 0 {
+		return nil, nil
+	}
+	if offset < mdaHeaderSize || offset >= size || length > size-mdaHeaderSize {
+		return nil, errors.Errorf("metadata area at %d has invalid location %d+%d", area.Offset, offset, length)
+	}
+	text := make([]byte, length)
+	first := length
+	if offset+length > size {
+		first = size - offset
+	}
+	if _, err := r.ReadAt(text[:first], int64(area.Offset+offset)); err != nil {
+		return nil, errors.Wrapf(err, "error reading metadata text at %d", area.Offset+offset)
+	}
+	if first < length {
+		if _, err := r.ReadAt(text[first:], int64(area.Offset+mdaHeaderSize)); err != nil {
+			return nil, errors.Wrapf(err, "error reading wrapped metadata text at %d", area.Offset+mdaHeaderSize)
+		}
+	}
+	if checksum(text) != crc {
+		return nil, errors.Errorf("metadata text at %d has bad checksum", area.Offset+offset)
+	}
+	return bytes.TrimRight(text, "\x00"), nil
+}
+
+// ReadPhysicalVolume reads the label, PV header, and most recent metadata from
+// a device or image file.
+func ReadPhysicalVolume(path string) (*PhysicalVolumeInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening %q", path)
+	}
+	defer f.Close()
+	label, err := ReadLabel(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading label from %q", path)
+	}
+	info := &PhysicalVolumeInfo{Label: *label}
+	var lastErr error
+	for _, area := range label.MetadataAreas {
+		text, err := ReadMetadataArea(f, area)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		info.Metadata = text
+		return info, nil
+	}
+	if lastErr != nil {
+		return nil, errors.Wrapf(lastErr, "error reading metadata from %q", path)
+	}
+	return info, nil
+}
+
+// formatUUID formats a 32-character UUID using the 6-4-4-4-4-4-6 grouping
+// that lvm uses when it displays them.
+func formatUUID(b []byte) string {
+	groups := []int{6, 4, 4, 4, 4, 4, 6}
+	s := make([]byte, 0, 38)
+	for i, n := range groups {
+		if i > 0 {
+			s = append(s, '-')
+		}
+		s = append(s, b[:n]...)
+		b = b[n:]
+	}
+	return string(s)
+}
+
+// checksum computes the CRC which lvm uses for labels and metadata: CRC-32
+// with the usual reflected polynomial, but a nonstandard initial value and no
+// final inversion.
+func checksum(b []byte) uint32 {
+	crc := uint32(initialCRC)
+	for _, c := range b {
+		crc ^= uint32(c)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = (crc >> 1) ^ 0xedb88320
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
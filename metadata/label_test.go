@@ -0,0 +1,85 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildImage constructs a physical volume image with a label in sector 1 and
+// a single metadata area which holds text, starting at textOffset within the
+// area.
+func buildImage(text []byte, mdaOffset, mdaSize, textOffset uint64) []byte {
+	image := make([]byte, mdaOffset+mdaSize+sectorSize)
+
+	label := image[sectorSize : 2*sectorSize]
+	copy(label[0:8], labelID)
+	binary.LittleEndian.PutUint64(label[8:16], 1)
+	binary.LittleEndian.PutUint32(label[20:24], 32)
+	copy(label[24:32], labelType)
+	pvh := label[32:]
+	copy(pvh[0:32], "Bs15T9bZUFxKLbnRaMM45WS0cFEy3fzV")
+	binary.LittleEndian.PutUint64(pvh[32:40], uint64(len(image)))
+	// one data area, then one metadata area, each list terminated by zeroes
+	binary.LittleEndian.PutUint64(pvh[40:48], mdaOffset+mdaSize)
+	binary.LittleEndian.PutUint64(pvh[72:80], mdaOffset)
+	binary.LittleEndian.PutUint64(pvh[80:88], mdaSize)
+	binary.LittleEndian.PutUint32(label[16:20], checksum(label[20:]))
+
+	mda := image[mdaOffset : mdaOffset+mdaSize]
+	copy(mda[4:20], mdaMagic)
+	binary.LittleEndian.PutUint32(mda[20:24], 1)
+	binary.LittleEndian.PutUint64(mda[24:32], mdaOffset)
+	binary.LittleEndian.PutUint64(mda[32:40], mdaSize)
+	binary.LittleEndian.PutUint64(mda[40:48], textOffset)
+	binary.LittleEndian.PutUint64(mda[48:56], uint64(len(text)))
+	binary.LittleEndian.PutUint32(mda[56:60], checksum(text))
+	for i, c := range text {
+		pos := textOffset + uint64(i)
+		if pos >= mdaSize {
+			pos = pos - mdaSize + mdaHeaderSize
+		}
+		mda[pos] = c
+	}
+	binary.LittleEndian.PutUint32(mda[0:4], checksum(mda[4:mdaHeaderSize]))
+	return image
+}
+
+func TestReadLabel(t *testing.T) {
+	image := buildImage(testArchive, 4096, 1<<20, 512)
+	label, err := ReadLabel(bytes.NewReader(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if label.Sector != 1 || label.UUID != "Bs15T9-bZUF-xKLb-nRaM-M45W-S0cF-Ey3fzV" || label.DeviceSize != uint64(len(image)) {
+		t.Fatalf("unexpected label: %+v", label)
+	}
+	if len(label.DataAreas) != 1 || len(label.MetadataAreas) != 1 || label.MetadataAreas[0] != (DiskArea{Offset: 4096, Size: 1 << 20}) {
+		t.Fatalf("unexpected areas: %+v", label)
+	}
+	image[sectorSize+100]++
+	if _, err = ReadLabel(bytes.NewReader(image)); err == nil {
+		t.Fatalf("expected a corrupted label to be rejected")
+	}
+}
+
+func TestReadMetadataArea(t *testing.T) {
+	const mdaSize = 8192
+	for _, textOffset := range []uint64{512, mdaSize - 1000} {
+		image := buildImage(testArchive, 4096, mdaSize, textOffset)
+		text, err := ReadMetadataArea(bytes.NewReader(image), DiskArea{Offset: 4096, Size: mdaSize})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(text, testArchive) {
+			t.Fatalf("metadata text at offset %d was not read back correctly", textOffset)
+		}
+		vg, err := ParseVolumeGroup(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vg.Name != "loopback" {
+			t.Fatalf("unexpected volume group name %q", vg.Name)
+		}
+	}
+}
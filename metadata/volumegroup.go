@@ -0,0 +1,705 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VolumeGroup is the description of a volume group that is stored in a
+// metadata area or in a backup or archive file.  Sizes and offsets which LVM
+// records in sectors are kept in sectors, and offsets into physical volumes are
+// kept in extents, just as they are in the text.
+type VolumeGroup struct {
+	// Information from the header of a backup or archive file.
+	Contents     string
+	Version      int64
+	Description  string
+	CreationHost string
+	CreationTime int64
+
+	Name            string
+	ID              string
+	SequenceNumber  int64
+	Format          string
+	Status          []string
+	Flags           []string
+	Tags            []string
+	SystemID        string
+	LockType        string
+	ExtentSize      int64
+	MaxLV           int64
+	MaxPV           int64
+	MetadataCopies  int64
+	PhysicalVolumes []PhysicalVolume
+	LogicalVolumes  []LogicalVolume
+
+	// raw is the parsed file that the VolumeGroup was read from, if it
+	// was read from one.
+	raw *Section
+}
+
+// PhysicalVolume is the description of a physical volume in a volume group.
+type PhysicalVolume struct {
+	Name       string
+	ID         string
+	Device     string
+	Status     []string
+	Flags      []string
+	Tags       []string
+	DeviceSize int64
+	PEStart    int64
+	PECount    int64
+
+	raw *Section
+}
+
+// LogicalVolume is the description of a logical volume in a volume group.
+type LogicalVolume struct {
+	Name         string
+	ID           string
+	Status       []string
+	Flags        []string
+	Tags         []string
+	CreationHost string
+	CreationTime int64
+	Segments     []Segment
+
+	raw *Section
+}
+
+// Segment is one contiguous range of extents of a logical volume.  Which of
+// the type-specific fields are set depends on Type.
+type Segment struct {
+	Name        string
+	StartExtent int64
+	ExtentCount int64
+	Type        string
+	Tags        []string
+
+	// Striped, mirrored, and RAID segments.  AreaKey is the name of the
+	// setting which lists the areas: "stripes", "mirrors", or "raids".  If
+	// it is not set, "stripes" is used.
+	StripeSize int64
+	AreaKey    string
+	Stripes    []Area
+
+	// Thin pool segments.
+	Metadata      string
+	Pool          string
+	TransactionID int64
+	ChunkSize     int64
+	Discards      string
+	ZeroNewBlocks int64
+	Messages      []ThinMessage
+
+	// Thin volume segments.
+	ThinPool       string
+	DeviceID       int64
+	Origin         string
+	ExternalOrigin string
+
+	raw *Section
+}
+
+// Area is a range of extents on a physical volume, or of another logical
+// volume, which backs part of a segment.
+type Area struct {
+	Name   string
+	Offset int64
+}
+
+// ThinMessage is a message which LVM has queued for delivery to a thin pool,
+// recording the creation of a thin volume or the deletion of a thin device.
+type ThinMessage struct {
+	Name   string
+	Create string
+	Delete int64
+}
+
+// HasStatus returns true if the physical volume's status includes flag.
+func (pv *PhysicalVolume) HasStatus(flag string) bool {
+	return contains(pv.Status, flag)
+}
+
+// HasStatus returns true if the logical volume's status includes flag.
+func (lv *LogicalVolume) HasStatus(flag string) bool {
+	return contains(lv.Status, flag)
+}
+
+// PhysicalVolume returns the physical volume with the specified name, as it
+// appears in stripe lists ("pv0"), if there is one.
+func (vg *VolumeGroup) PhysicalVolume(name string) (*PhysicalVolume, bool) {
+	for i := range vg.PhysicalVolumes {
+		if vg.PhysicalVolumes[i].Name == name {
+			return &vg.PhysicalVolumes[i], true
+		}
+	}
+	return nil, false
+}
+
+// LogicalVolume returns the logical volume with the specified name, if there is
+// one.
+func (vg *VolumeGroup) LogicalVolume(name string) (*LogicalVolume, bool) {
+	for i := range vg.LogicalVolumes {
+		if vg.LogicalVolumes[i].Name == name {
+			return &vg.LogicalVolumes[i], true
+		}
+	}
+	return nil, false
+}
+
+// ParseVolumeGroup parses the contents of a metadata area or of a backup or
+// archive file.
+func ParseVolumeGroup(data []byte) (*VolumeGroup, error) {
+	root, err := ParseConfig(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing volume group metadata")
+	}
+	return NewVolumeGroup(root)
+}
+
+// NewVolumeGroup builds a VolumeGroup from a parsed metadata file.
+func NewVolumeGroup(root *Section) (*VolumeGroup, error) {
+	if len(root.Sections) != 1 {
+		return nil, errors.Errorf("expected metadata for one volume group, found %d sections", len(root.Sections))
+	}
+	s := root.Sections[0]
+	vg := &VolumeGroup{Name: s.Name, raw: root}
+	vg.Contents, _ = root.String("contents")
+	vg.Version, _ = root.Int("version")
+	vg.Description, _ = root.String("description")
+	vg.CreationHost, _ = root.String("creation_host")
+	vg.CreationTime, _ = root.Int("creation_time")
+	vg.ID, _ = s.String("id")
+	vg.SequenceNumber, _ = s.Int("seqno")
+	vg.Format, _ = s.String("format")
+	vg.Status, _ = s.Strings("status")
+	vg.Flags, _ = s.Strings("flags")
+	vg.Tags, _ = s.Strings("tags")
+	vg.SystemID, _ = s.String("system_id")
+	vg.LockType, _ = s.String("lock_type")
+	vg.ExtentSize, _ = s.Int("extent_size")
+	vg.MaxLV, _ = s.Int("max_lv")
+	vg.MaxPV, _ = s.Int("max_pv")
+	vg.MetadataCopies, _ = s.Int("metadata_copies")
+	if pvs, ok := s.Section("physical_volumes"); ok {
+		for _, p := range pvs.Sections {
+			pv := PhysicalVolume{Name: p.Name, raw: p}
+			pv.ID, _ = p.String("id")
+			pv.Device, _ = p.String("device")
+			pv.Status, _ = p.Strings("status")
+			pv.Flags, _ = p.Strings("flags")
+			pv.Tags, _ = p.Strings("tags")
+			pv.DeviceSize, _ = p.Int("dev_size")
+			pv.PEStart, _ = p.Int("pe_start")
+			pv.PECount, _ = p.Int("pe_count")
+			vg.PhysicalVolumes = append(vg.PhysicalVolumes, pv)
+		}
+	}
+	if lvs, ok := s.Section("logical_volumes"); ok {
+		for _, l := range lvs.Sections {
+			lv, err := newLogicalVolume(l)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error reading logical volume %q", l.Name)
+			}
+			vg.LogicalVolumes = append(vg.LogicalVolumes, lv)
+		}
+	}
+	return vg, nil
+}
+
+func newLogicalVolume(l *Section) (LogicalVolume, error) {
+	lv := LogicalVolume{Name: l.Name, raw: l}
+	lv.ID, _ = l.String("id")
+	lv.Status, _ = l.Strings("status")
+	lv.Flags, _ = l.Strings("flags")
+	lv.Tags, _ = l.Strings("tags")
+	lv.CreationHost, _ = l.String("creation_host")
+	lv.CreationTime, _ = l.Int("creation_time")
+	count, _ := l.Int("segment_count")
+	for i := int64(1); i <= count; i++ {
+		name := fmt.Sprintf("segment%d", i)
+		s, ok := l.Section(name)
+		if !ok {
+			return LogicalVolume{}, errors.Errorf("%s is missing", name)
+		}
+		seg, err := newSegment(s)
+		if err != nil {
+			return LogicalVolume{}, errors.Wrapf(err, "error reading %s", name)
+		}
+		lv.Segments = append(lv.Segments, seg)
+	}
+	return lv, nil
+}
+
+func newSegment(s *Section) (Segment, error) {
+	seg := Segment{Name: s.Name, raw: s}
+	seg.StartExtent, _ = s.Int("start_extent")
+	seg.ExtentCount, _ = s.Int("extent_count")
+	seg.Type, _ = s.String("type")
+	seg.Tags, _ = s.Strings("tags")
+	seg.StripeSize, _ = s.Int("stripe_size")
+	for _, key := range []string{"stripes", "mirrors", "raids"} {
+		v, ok := s.Get(key)
+		if !ok {
+			continue
+		}
+		list, ok := v.([]interface{})
+		if !ok {
+			return Segment{}, errors.Errorf("%q is not a list", key)
+		}
+		areas, err := newAreas(list)
+		if err != nil {
+			return Segment{}, errors.Wrapf(err, "error reading %q", key)
+		}
+		seg.AreaKey = key
+		seg.Stripes = areas
+	}
+	seg.Metadata, _ = s.String("metadata")
+	seg.Pool, _ = s.String("pool")
+	seg.TransactionID, _ = s.Int("transaction_id")
+	seg.ChunkSize, _ = s.Int("chunk_size")
+	seg.Discards, _ = s.String("discards")
+	seg.ZeroNewBlocks, _ = s.Int("zero_new_blocks")
+	seg.ThinPool, _ = s.String("thin_pool")
+	seg.DeviceID, _ = s.Int("device_id")
+	seg.Origin, _ = s.String("origin")
+	seg.ExternalOrigin, _ = s.String("external_origin")
+	for _, m := range s.Sections {
+		if seg.Type != "thin-pool" || !strings.HasPrefix(m.Name, "message") {
+			continue
+		}
+		msg := ThinMessage{Name: m.Name}
+		msg.Create, _ = m.String("create")
+		msg.Delete, _ = m.Int("delete")
+		seg.Messages = append(seg.Messages, msg)
+	}
+	return seg, nil
+}
+
+// newAreas reads a list of alternating names and offsets.  RAID segments list
+// only names, so their offsets are left as zero.
+func newAreas(list []interface{}) ([]Area, error) {
+	areas := []Area{}
+	for i := 0; i < len(list); i++ {
+		name, ok := list[i].(string)
+		if !ok {
+			return nil, errors.Errorf("expected a volume name at position %d", i)
+		}
+		area := Area{Name: name}
+		if i+1 < len(list) {
+			if offset, ok := list[i+1].(int64); ok {
+				area.Offset = offset
+				i++
+			}
+		}
+		areas = append(areas, area)
+	}
+	return areas, nil
+}
+
+// Section converts the VolumeGroup back into a tree which can be written in
+// LVM's text format.  If the VolumeGroup was parsed, the settings which it
+// represents are updated in a copy of the parsed tree, so that settings which
+// this package does not model, like those of cache segments, are kept.
+func (vg *VolumeGroup) Section() *Section {
+	root := copyOrNew(vg.raw, "")
+	setString(root, "contents", vg.Contents)
+	setInt(root, "version", vg.Version)
+	setString(root, "description", vg.Description)
+	setString(root, "creation_host", vg.CreationHost)
+	setInt(root, "creation_time", vg.CreationTime)
+	s := &Section{Name: vg.Name}
+	if len(root.Sections) == 1 {
+		s = root.Sections[0]
+		s.Name = vg.Name
+	} else {
+		root.Sections = []*Section{s}
+	}
+	s.Set("id", vg.ID)
+	s.Set("seqno", vg.SequenceNumber)
+	setString(s, "format", vg.Format)
+	s.Set("status", stringList(vg.Status))
+	s.Set("flags", stringList(vg.Flags))
+	setStrings(s, "tags", vg.Tags)
+	setString(s, "system_id", vg.SystemID)
+	setString(s, "lock_type", vg.LockType)
+	s.Set("extent_size", vg.ExtentSize)
+	s.Set("max_lv", vg.MaxLV)
+	s.Set("max_pv", vg.MaxPV)
+	s.Set("metadata_copies", vg.MetadataCopies)
+	pvs := &Section{Name: "physical_volumes"}
+	for _, pv := range vg.PhysicalVolumes {
+		p := copyOrNew(pv.raw, pv.Name)
+		p.Set("id", pv.ID)
+		setString(p, "device", pv.Device)
+		p.Set("status", stringList(pv.Status))
+		p.Set("flags", stringList(pv.Flags))
+		setStrings(p, "tags", pv.Tags)
+		p.Set("dev_size", pv.DeviceSize)
+		p.Set("pe_start", pv.PEStart)
+		p.Set("pe_count", pv.PECount)
+		pvs.Sections = append(pvs.Sections, p)
+	}
+	s.SetSection(pvs)
+	if len(vg.LogicalVolumes) > 0 {
+		lvs := &Section{Name: "logical_volumes"}
+		for _, lv := range vg.LogicalVolumes {
+			lvs.Sections = append(lvs.Sections, lv.section())
+		}
+		s.SetSection(lvs)
+	} else {
+		removeSections(s, func(name string) bool { return name == "logical_volumes" })
+	}
+	return root
+}
+
+// areaCountKeys are the settings which record how many areas a segment has,
+// indexed by the setting which lists the areas.
+var areaCountKeys = map[string]string{
+	"stripes": "stripe_count",
+	"mirrors": "mirror_count",
+	"raids":   "device_count",
+}
+
+func (lv *LogicalVolume) section() *Section {
+	l := copyOrNew(lv.raw, lv.Name)
+	l.Set("id", lv.ID)
+	l.Set("status", stringList(lv.Status))
+	l.Set("flags", stringList(lv.Flags))
+	setStrings(l, "tags", lv.Tags)
+	setString(l, "creation_host", lv.CreationHost)
+	setInt(l, "creation_time", lv.CreationTime)
+	l.Set("segment_count", int64(len(lv.Segments)))
+	removeSections(l, func(name string) bool { return strings.HasPrefix(name, "segment") })
+	for i, seg := range lv.Segments {
+		l.Sections = append(l.Sections, seg.section(fmt.Sprintf("segment%d", i+1)))
+	}
+	return l
+}
+
+// section converts a segment back into a tree.  The settings of segment types
+// other than thin pools and thin volumes which aren't represented in the
+// Segment are carried over from the parsed segment unchanged.
+func (seg *Segment) section(name string) *Section {
+	s := copyOrNew(seg.raw, name)
+	s.Set("start_extent", seg.StartExtent)
+	s.Set("extent_count", seg.ExtentCount)
+	s.Set("type", seg.Type)
+	setStrings(s, "tags", seg.Tags)
+	switch seg.Type {
+	case "thin-pool":
+		s.Set("metadata", seg.Metadata)
+		s.Set("pool", seg.Pool)
+		s.Set("transaction_id", seg.TransactionID)
+		s.Set("chunk_size", seg.ChunkSize)
+		setString(s, "discards", seg.Discards)
+		s.Set("zero_new_blocks", seg.ZeroNewBlocks)
+		removeSections(s, func(name string) bool { return strings.HasPrefix(name, "message") })
+		for _, msg := range seg.Messages {
+			m := &Section{Name: msg.Name}
+			if msg.Create != "" {
+				m.Set("create", msg.Create)
+			} else {
+				m.Set("delete", msg.Delete)
+			}
+			s.Sections = append(s.Sections, m)
+		}
+	case "thin":
+		s.Set("thin_pool", seg.ThinPool)
+		s.Set("transaction_id", seg.TransactionID)
+		s.Set("device_id", seg.DeviceID)
+		setString(s, "origin", seg.Origin)
+		setString(s, "external_origin", seg.ExternalOrigin)
+	default:
+		if len(seg.Stripes) == 0 {
+			break
+		}
+		key := seg.AreaKey
+		if key == "" {
+			key = "stripes"
+		}
+		count := int64(len(seg.Stripes))
+		areas := []interface{}{}
+		for _, area := range seg.Stripes {
+			if key == "raids" {
+				// RAID areas are pairs of metadata and data
+				// subvolumes, without offsets.
+				areas = append(areas, area.Name)
+				if strings.Contains(area.Name, "_rmeta_") {
+					count--
+				}
+			} else {
+				areas = append(areas, area.Name, area.Offset)
+			}
+		}
+		s.Set(areaCountKeys[key], count)
+		setInt(s, "stripe_size", seg.StripeSize)
+		s.Set(key, areas)
+	}
+	return s
+}
+
+// Bytes returns the VolumeGroup in LVM's text format.
+func (vg *VolumeGroup) Bytes() []byte {
+	return vg.Section().Bytes()
+}
+
+// Validate checks the VolumeGroup for internal consistency: that extents are
+// allocated within the bounds of the physical volumes and are not allocated
+// twice, that segments are contiguous, and that every volume which is
+// referred to exists.
+func (vg *VolumeGroup) Validate() error {
+	if vg.Name == "" {
+		return errors.New("volume group has no name")
+	}
+	if vg.ExtentSize <= 0 {
+		return errors.Errorf("volume group %q has invalid extent size %d", vg.Name, vg.ExtentSize)
+	}
+	ids := map[string]string{}
+	type allocation struct {
+		start, end int64
+		owner      string
+	}
+	allocated := map[string][]allocation{}
+	for _, pv := range vg.PhysicalVolumes {
+		if other, ok := ids[pv.ID]; ok {
+			return errors.Errorf("physical volume %q has the same ID as %q", pv.Name, other)
+		}
+		ids[pv.ID] = pv.Name
+		allocated[pv.Name] = []allocation{}
+	}
+	for _, lv := range vg.LogicalVolumes {
+		if other, ok := ids[lv.ID]; ok {
+			return errors.Errorf("logical volume %q has the same ID as %q", lv.Name, other)
+		}
+		ids[lv.ID] = lv.Name
+	}
+	for _, lv := range vg.LogicalVolumes {
+		next := int64(0)
+		for _, seg := range lv.Segments {
+			if seg.StartExtent != next {
+				return errors.Errorf("logical volume %q: %s starts at extent %d, expected %d", lv.Name, seg.Name, seg.StartExtent, next)
+			}
+			next += seg.ExtentCount
+			for _, ref := range []string{seg.Metadata, seg.Pool, seg.ThinPool, seg.Origin, seg.ExternalOrigin} {
+				if ref == "" {
+					continue
+				}
+				if _, ok := vg.LogicalVolume(ref); !ok {
+					return errors.Errorf("logical volume %q: %s refers to unknown logical volume %q", lv.Name, seg.Name, ref)
+				}
+			}
+			if seg.Type != "striped" && seg.Type != "linear" {
+				for _, area := range seg.Stripes {
+					if _, ok := allocated[area.Name]; ok {
+						continue
+					}
+					if _, ok := vg.LogicalVolume(area.Name); !ok {
+						return errors.Errorf("logical volume %q: %s refers to unknown volume %q", lv.Name, seg.Name, area.Name)
+					}
+				}
+				continue
+			}
+			if len(seg.Stripes) == 0 {
+				return errors.Errorf("logical volume %q: %s has no stripes", lv.Name, seg.Name)
+			}
+			length := seg.ExtentCount / int64(len(seg.Stripes))
+			for _, area := range seg.Stripes {
+				pv, ok := vg.PhysicalVolume(area.Name)
+				if !ok {
+					return errors.Errorf("logical volume %q: %s refers to unknown physical volume %q", lv.Name, seg.Name, area.Name)
+				}
+				a := allocation{start: area.Offset, end: area.Offset + length, owner: lv.Name}
+				if a.start < 0 || a.end > pv.PECount {
+					return errors.Errorf("logical volume %q: %s uses extents %d-%d of %q, which has %d", lv.Name, seg.Name, a.start, a.end-1, pv.Name, pv.PECount)
+				}
+				for _, b := range allocated[pv.Name] {
+					if a.start < b.end && b.start < a.end {
+						return errors.Errorf("logical volume %q: %s overlaps logical volume %q on %q", lv.Name, seg.Name, b.owner, pv.Name)
+					}
+				}
+				allocated[pv.Name] = append(allocated[pv.Name], a)
+			}
+		}
+	}
+	return nil
+}
+
+// Change describes one difference between two versions of a volume group's
+// metadata.
+type Change struct {
+	// Kind is "added", "removed", or "modified".
+	Kind string
+	// Object is "vg", "pv", or "lv".
+	Object string
+	Name   string
+	Detail string
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s %s %q", c.Kind, c.Object, c.Name)
+	if c.Detail != "" {
+		s += ": " + c.Detail
+	}
+	return s
+}
+
+// Diff returns the differences between two versions of a volume group's
+// metadata.  Physical and logical volumes are matched by ID, so a renamed
+// volume is reported as modified rather than as removed and added.
+func Diff(old, updated *VolumeGroup) []Change {
+	changes := []Change{}
+	modified := func(object, name, field string, a, b interface{}) {
+		if fmt.Sprintf("%v", a) != fmt.Sprintf("%v", b) {
+			changes = append(changes, Change{Kind: "modified", Object: object, Name: name, Detail: fmt.Sprintf("%s changed from %v to %v", field, a, b)})
+		}
+	}
+	modified("vg", updated.Name, "name", old.Name, updated.Name)
+	modified("vg", updated.Name, "seqno", old.SequenceNumber, updated.SequenceNumber)
+	modified("vg", updated.Name, "status", old.Status, updated.Status)
+	modified("vg", updated.Name, "flags", old.Flags, updated.Flags)
+	modified("vg", updated.Name, "tags", old.Tags, updated.Tags)
+	modified("vg", updated.Name, "system_id", old.SystemID, updated.SystemID)
+	modified("vg", updated.Name, "extent_size", old.ExtentSize, updated.ExtentSize)
+
+	oldPVs := map[string]PhysicalVolume{}
+	for _, pv := range old.PhysicalVolumes {
+		oldPVs[pv.ID] = pv
+	}
+	for _, pv := range updated.PhysicalVolumes {
+		before, ok := oldPVs[pv.ID]
+		if !ok {
+			changes = append(changes, Change{Kind: "added", Object: "pv", Name: pv.Device})
+			continue
+		}
+		delete(oldPVs, pv.ID)
+		modified("pv", pv.Device, "device", before.Device, pv.Device)
+		modified("pv", pv.Device, "status", before.Status, pv.Status)
+		modified("pv", pv.Device, "pe_count", before.PECount, pv.PECount)
+	}
+	for _, pv := range sortedPVs(oldPVs) {
+		changes = append(changes, Change{Kind: "removed", Object: "pv", Name: pv.Device})
+	}
+
+	oldLVs := map[string]LogicalVolume{}
+	for _, lv := range old.LogicalVolumes {
+		oldLVs[lv.ID] = lv
+	}
+	for _, lv := range updated.LogicalVolumes {
+		before, ok := oldLVs[lv.ID]
+		if !ok {
+			changes = append(changes, Change{Kind: "added", Object: "lv", Name: lv.Name})
+			continue
+		}
+		delete(oldLVs, lv.ID)
+		modified("lv", lv.Name, "name", before.Name, lv.Name)
+		modified("lv", lv.Name, "status", before.Status, lv.Status)
+		modified("lv", lv.Name, "flags", before.Flags, lv.Flags)
+		modified("lv", lv.Name, "tags", before.Tags, lv.Tags)
+		modified("lv", lv.Name, "segments", describeSegments(before.Segments), describeSegments(lv.Segments))
+	}
+	for _, lv := range sortedLVs(oldLVs) {
+		changes = append(changes, Change{Kind: "removed", Object: "lv", Name: lv.Name})
+	}
+	return changes
+}
+
+func describeSegments(segs []Segment) string {
+	descriptions := []string{}
+	for _, seg := range segs {
+		d := fmt.Sprintf("%s:%d+%d", seg.Type, seg.StartExtent, seg.ExtentCount)
+		for _, area := range seg.Stripes {
+			d += fmt.Sprintf(",%s@%d", area.Name, area.Offset)
+		}
+		descriptions = append(descriptions, d)
+	}
+	return "[" + strings.Join(descriptions, " ") + "]"
+}
+
+func sortedPVs(pvs map[string]PhysicalVolume) []PhysicalVolume {
+	list := []PhysicalVolume{}
+	for _, pv := range pvs {
+		list = append(list, pv)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func sortedLVs(lvs map[string]LogicalVolume) []LogicalVolume {
+	list := []LogicalVolume{}
+	for _, lv := range lvs {
+		list = append(list, lv)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func contains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList(strs []string) []interface{} {
+	list := make([]interface{}, 0, len(strs))
+	for _, s := range strs {
+		list = append(list, s)
+	}
+	return list
+}
+
+// copyOrNew returns a copy of a parsed section, or a new, empty one if there
+// isn't one.
+func copyOrNew(raw *Section, name string) *Section {
+	if raw == nil {
+		return &Section{Name: name}
+	}
+	s := raw.Copy()
+	s.Name = name
+	return s
+}
+
+// removeSections removes the subsections whose names match.
+func removeSections(s *Section, match func(name string) bool) {
+	kept := s.Sections[:0]
+	for _, sub := range s.Sections {
+		if !match(sub.Name) {
+			kept = append(kept, sub)
+		}
+	}
+	s.Sections = kept
+}
+
+// setString sets an optional setting, or removes it if value is empty.
+func setString(s *Section, key, value string) {
+	if value != "" {
+		s.Set(key, value)
+	} else {
+		s.Delete(key)
+	}
+}
+
+// setInt sets an optional setting, or removes it if value is zero.
+func setInt(s *Section, key string, value int64) {
+	if value != 0 {
+		s.Set(key, value)
+	} else {
+		s.Delete(key)
+	}
+}
+
+// setStrings sets an optional list setting, or removes it if it's empty.
+func setStrings(s *Section, key string, values []string) {
+	if len(values) > 0 {
+		s.Set(key, stringList(values))
+	} else {
+		s.Delete(key)
+	}
+}
@@ -0,0 +1,502 @@
+package metadata
+
+import (
+	"strings"
+	"testing"
+)
+
+var testArchive = []byte(`# Generated by LVM2 version 2.02.171(2) (2017-05-03): Mon Jul 10 10:22:14 2017
+
+contents = "Text Format Volume Group"
+version = 1
+
+description = "Created *before* executing 'lvcreate --snapshot --name layer.b loopback/layer.a'"
+
+creation_host = "localhost"	# Linux localhost 4.11.8-300.fc26.x86_64 #1 SMP Thu Jun 29 20:09:48 UTC 2017 x86_64
+creation_time = 1499696534	# Mon Jul 10 10:22:14 2017
+
+loopback {
+	id = "Ju0Ma8-6dZm-qWmb-xyDr-TnXS-lTbE-Ubbg3K"
+	seqno = 7
+	format = "lvm2"			# informational
+	status = ["RESIZEABLE", "READ", "WRITE"]
+	flags = []
+	extent_size = 8192		# 4 Megabytes
+	max_lv = 0
+	max_pv = 0
+	metadata_copies = 0
+
+	physical_volumes {
+
+		pv0 {
+			id = "Bs15T9-bZUF-xKLb-nRaM-M45W-S0cF-Ey3fzV"
+			device = "/dev/loop0"	# Hint only
+
+			status = ["ALLOCATABLE"]
+			flags = []
+			dev_size = 20971520	# 10 Gigabytes
+			pe_start = 2048
+			pe_count = 2559	# 9.99609 Gigabytes
+		}
+	}
+
+	logical_volumes {
+
+		pool {
+			id = "WIcSPU-wPkY-65mB-VMnj-8v3x-6L9H-dyS4NB"
+			status = ["READ", "WRITE", "VISIBLE"]
+			flags = []
+			creation_time = 1499696500	# 2017-07-10 10:21:40 -0400
+			creation_host = "localhost"
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 2133	# 8.33203 Gigabytes
+
+				type = "thin-pool"
+				metadata = "pool_tmeta"
+				pool = "pool_tdata"
+				transaction_id = 2
+				chunk_size = 128	# 64 Kilobytes
+				discards = "passdown"
+				zero_new_blocks = 1
+
+				message1 {
+					create = "layer.b"
+				}
+			}
+		}
+
+		layer.a {
+			id = "YiDGjo-62c9-Lg0c-6p6g-LYCa-VdXv-O71fM3"
+			status = ["READ", "WRITE", "VISIBLE"]
+			flags = []
+			tags = ["lvmgo.id=a"]
+			creation_time = 1499696510
+			creation_host = "localhost"
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 2133
+
+				type = "thin"
+				thin_pool = "pool"
+				transaction_id = 0
+				device_id = 1
+			}
+		}
+
+		pool_tmeta {
+			id = "UncqPW-cA25-YPg7-n7Qh-5zx5-zY2W-M0nWZx"
+			status = ["READ", "WRITE"]
+			flags = []
+			creation_time = 1499696500
+			creation_host = "localhost"
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 3
+
+				type = "striped"
+				stripe_count = 1	# linear
+
+				stripes = [
+					"pv0", 2136
+				]
+			}
+		}
+
+		pool_tdata {
+			id = "JIgpFL-Qjj1-Wqc8-PMXm-80VC-X2iv-KpFXYq"
+			status = ["READ", "WRITE"]
+			flags = []
+			creation_time = 1499696500
+			creation_host = "localhost"
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 2133
+
+				type = "striped"
+				stripe_count = 1	# linear
+
+				stripes = [
+					"pv0", 0
+				]
+			}
+		}
+	}
+}
+`)
+
+func TestParseVolumeGroup(t *testing.T) {
+	vg, err := ParseVolumeGroup(testArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vg.Name != "loopback" || vg.SequenceNumber != 7 || vg.ExtentSize != 8192 || vg.CreationTime != 1499696534 {
+		t.Fatalf("unexpected volume group header: %+v", vg)
+	}
+	if len(vg.PhysicalVolumes) != 1 || vg.PhysicalVolumes[0].Device != "/dev/loop0" || vg.PhysicalVolumes[0].PECount != 2559 {
+		t.Fatalf("unexpected physical volumes: %+v", vg.PhysicalVolumes)
+	}
+	pool, ok := vg.LogicalVolume("pool")
+	if !ok || len(pool.Segments) != 1 || pool.Segments[0].Type != "thin-pool" || pool.Segments[0].ChunkSize != 128 {
+		t.Fatalf("unexpected thin pool: %+v", pool)
+	}
+	if len(pool.Segments[0].Messages) != 1 || pool.Segments[0].Messages[0].Create != "layer.b" {
+		t.Fatalf("unexpected thin pool messages: %+v", pool.Segments[0].Messages)
+	}
+	thin, ok := vg.LogicalVolume("layer.a")
+	if !ok || thin.Segments[0].ThinPool != "pool" || thin.Segments[0].DeviceID != 1 || thin.Tags[0] != "lvmgo.id=a" {
+		t.Fatalf("unexpected thin volume: %+v", thin)
+	}
+	tdata, ok := vg.LogicalVolume("pool_tdata")
+	if !ok || len(tdata.Segments[0].Stripes) != 1 || tdata.Segments[0].Stripes[0] != (Area{Name: "pv0", Offset: 0}) {
+		t.Fatalf("unexpected stripes: %+v", tdata)
+	}
+	if err = vg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	root, err := ParseConfig(testArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseConfig(root.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again.Bytes()) != string(root.Bytes()) {
+		t.Fatalf("config changed when written and read back:\n%s\n%s", root.Bytes(), again.Bytes())
+	}
+	vg, err := ParseVolumeGroup(testArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vg2, err := ParseVolumeGroup(vg.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(vg, vg2); len(changes) != 0 {
+		t.Fatalf("volume group changed when written and read back: %v", changes)
+	}
+}
+
+func TestDiffAndValidate(t *testing.T) {
+	old, err := ParseVolumeGroup(testArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := ParseVolumeGroup(testArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated.SequenceNumber++
+	updated.LogicalVolumes = append(updated.LogicalVolumes[:1], updated.LogicalVolumes[2:]...)
+	tmeta, _ := updated.LogicalVolume("pool_tmeta")
+	tmeta.Segments[0].Stripes[0].Offset = 0
+	changes := Diff(old, updated)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %v", changes)
+	}
+	if changes[0].Object != "vg" || changes[1].Name != "pool_tmeta" || changes[2].Kind != "removed" || changes[2].Name != "layer.a" {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+	if err = updated.Validate(); err == nil || !strings.Contains(err.Error(), "overlaps") {
+		t.Fatalf("expected overlapping extents to be rejected, got %v", err)
+	}
+}
+
+var testRaidCacheArchive = []byte(`contents = "Text Format Volume Group"
+version = 1
+
+vg {
+	id = "Ju0Ma8-6dZm-qWmb-xyDr-TnXS-lTbE-Ubbg3L"
+	seqno = 12
+	format = "lvm2"
+	status = ["RESIZEABLE", "READ", "WRITE"]
+	flags = []
+	extent_size = 8192
+	max_lv = 0
+	max_pv = 0
+	metadata_copies = 0
+
+	physical_volumes {
+
+		pv0 {
+			id = "Bs15T9-bZUF-xKLb-nRaM-M45W-S0cF-Ey3fzA"
+			device = "/dev/sdb"
+			status = ["ALLOCATABLE"]
+			flags = []
+			dev_size = 20971520
+			pe_start = 2048
+			pe_count = 2559
+		}
+
+		pv1 {
+			id = "Bs15T9-bZUF-xKLb-nRaM-M45W-S0cF-Ey3fzB"
+			device = "/dev/sdc"
+			status = ["ALLOCATABLE"]
+			flags = []
+			dev_size = 20971520
+			pe_start = 2048
+			pe_count = 2559
+		}
+	}
+
+	logical_volumes {
+
+		mirror {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000001"
+			status = ["READ", "WRITE", "VISIBLE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 10
+				type = "raid1"
+				device_count = 2
+				region_size = 4096
+				raids = ["mirror_rmeta_0", "mirror_rimage_0", "mirror_rmeta_1", "mirror_rimage_1"]
+			}
+		}
+
+		mirror_rmeta_0 {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000002"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 1
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv0", 0]
+			}
+		}
+
+		mirror_rimage_0 {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000003"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 10
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv0", 1]
+			}
+		}
+
+		mirror_rmeta_1 {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000004"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 1
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv1", 0]
+			}
+		}
+
+		mirror_rimage_1 {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000005"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 10
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv1", 1]
+			}
+		}
+
+		cached {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000006"
+			status = ["READ", "WRITE", "VISIBLE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 100
+				type = "cache"
+				cache_pool = "cpool"
+				origin = "cached_corig"
+				metadata_format = 2
+				chunk_size = 128
+				cache_mode = "writethrough"
+				policy = "smq"
+
+				policy_settings {
+					migration_threshold = 2048
+				}
+			}
+		}
+
+		cpool {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000007"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 20
+				type = "cache-pool"
+				data = "cpool_cdata"
+				metadata = "cpool_cmeta"
+			}
+		}
+
+		cpool_cdata {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000008"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 20
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv1", 100]
+			}
+		}
+
+		cpool_cmeta {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000009"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 2
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv1", 120]
+			}
+		}
+
+		cached_corig {
+			id = "Kb3mJx-0001-0001-0001-0001-0001-000010"
+			status = ["READ", "WRITE"]
+			flags = []
+			segment_count = 1
+
+			segment1 {
+				start_extent = 0
+				extent_count = 100
+				type = "striped"
+				stripe_count = 1
+				stripes = ["pv0", 100]
+			}
+		}
+	}
+}
+`)
+
+func TestRoundTripRaidAndCache(t *testing.T) {
+	root, err := ParseConfig(testRaidCacheArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, archive := range [][]byte{testArchive, testRaidCacheArchive} {
+		parsed, err := ParseConfig(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vg, err := NewVolumeGroup(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(vg.Bytes()) != string(parsed.Bytes()) {
+			t.Fatalf("volume group %q changed when written back:\n%s\n%s", vg.Name, parsed.Bytes(), vg.Bytes())
+		}
+	}
+
+	vg, err := NewVolumeGroup(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	mirror, _ := vg.LogicalVolume("mirror")
+	if mirror.Segments[0].AreaKey != "raids" || len(mirror.Segments[0].Stripes) != 4 {
+		t.Fatalf("unexpected raid1 segment: %+v", mirror.Segments[0])
+	}
+	cached, _ := vg.LogicalVolume("cached")
+	if len(cached.Segments[0].Messages) != 0 {
+		t.Fatalf("cache policy settings read as thin pool messages: %+v", cached.Segments[0].Messages)
+	}
+
+	// Modify the volume group, and check that settings which aren't
+	// modelled are still there.
+	vg.SequenceNumber++
+	mirror.Segments[0].ExtentCount = 20
+	cached.Tags = []string{"modified"}
+	updated, err := ParseConfig(vg.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lvs, _ := updated.Sections[0].Section("logical_volumes")
+	m, _ := lvs.Section("mirror")
+	seg, _ := m.Section("segment1")
+	if count, _ := seg.Int("extent_count"); count != 20 {
+		t.Errorf("expected the raid1 segment's extent count to be updated, got %d", count)
+	}
+	if _, ok := seg.Get("stripes"); ok {
+		t.Errorf("raid1 segment was written with stripes: %+v", seg)
+	}
+	if count, _ := seg.Int("device_count"); count != 2 {
+		t.Errorf("expected a raid1 device count of 2, got %d", count)
+	}
+	if size, _ := seg.Int("region_size"); size != 4096 {
+		t.Errorf("raid1 region size was lost: %+v", seg)
+	}
+	c, _ := lvs.Section("cached")
+	if tags, _ := c.Strings("tags"); len(tags) != 1 || tags[0] != "modified" {
+		t.Errorf("expected the cached volume's tags to be updated, got %v", tags)
+	}
+	seg, _ = c.Section("segment1")
+	if pool, _ := seg.String("cache_pool"); pool != "cpool" {
+		t.Errorf("cache pool was lost: %+v", seg)
+	}
+	if settings, ok := seg.Section("policy_settings"); !ok || len(settings.Values) != 1 {
+		t.Errorf("cache policy settings were lost: %+v", seg)
+	}
+
+	// A segment which wasn't read from a file is written from scratch.
+	vg.LogicalVolumes = append(vg.LogicalVolumes, LogicalVolume{
+		Name:     "new",
+		ID:       "Kb3mJx-0001-0001-0001-0001-0001-000011",
+		Segments: []Segment{{Type: "striped", ExtentCount: 5, Stripes: []Area{{Name: "pv0", Offset: 200}}}},
+	})
+	again, err := ParseVolumeGroup(vg.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(vg, again); len(changes) != 0 {
+		t.Fatalf("volume group changed when written and read back: %v", changes)
+	}
+}
@@ -0,0 +1,168 @@
+package lvm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxVolumeNameLength is the longest name that lvm allows a logical
+	// volume to have.
+	MaxVolumeNameLength = 127
+	// IDTag is the prefix of the tag which records the ID of a logical volume
+	// whose name had to be shortened.
+	IDTag = "lvm-go.id="
+)
+
+// Namer converts IDs into the names of logical volumes, and back.
+type Namer interface {
+	// VolumeName returns the name to use for the logical volume for an ID,
+	// along with any tags which should be set on it.
+	VolumeName(id string) (name string, tags []string, err error)
+	// ID returns the ID for a logical volume with the specified name and
+	// tags, or false if the volume's name was not produced by VolumeName.
+	ID(name string, tags ...string) (string, bool)
+}
+
+// PrefixNamer names a logical volume by adding a prefix to its ID.  IDs which
+// would produce names longer than lvm allows are replaced with a hash of the
+// ID, and the ID is recorded in a tag instead.
+type PrefixNamer struct {
+	Prefix string
+}
+
+// DefaultNamer is the Namer used by Clients which don't specify one.
+var DefaultNamer Namer = PrefixNamer{Prefix: "layer."}
+
+// VolumeName returns the name for the logical volume for an ID.
+func (p PrefixNamer) VolumeName(id string) (string, []string, error) {
+	if id == "" {
+		return "", nil, errors.New("empty ID")
+	}
+	name := p.Prefix + id
+	var tags []string
+	if len(name) > MaxVolumeNameLength {
+		sum := sha256.Sum256([]byte(id))
+		name = p.Prefix + hex.EncodeToString(sum[:])
+		if len(name) > MaxVolumeNameLength {
+			return "", nil, errors.Errorf("prefix %q is too long", p.Prefix)
+		}
+		tag := IDTag + id
+		if err := ValidateTag(tag); err != nil {
+			return "", nil, errors.Wrapf(err, "can't record ID %q", id)
+		}
+		tags = append(tags, tag)
+	}
+	if err := ValidateVolumeName(name); err != nil {
+		return "", nil, errors.Wrapf(err, "can't name a volume for ID %q", id)
+	}
+	return name, tags, nil
+}
+
+// ID returns the ID of a logical volume that was named by VolumeName.
+func (p PrefixNamer) ID(name string, tags ...string) (string, bool) {
+	if !strings.HasPrefix(name, p.Prefix) || len(name) == len(p.Prefix) {
+		return "", false
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, IDTag) {
+			return strings.TrimPrefix(tag, IDTag), true
+		}
+	}
+	return strings.TrimPrefix(name, p.Prefix), true
+}
+
+// reservedVolumeNameParts are the strings which lvm uses in the names of the
+// logical volumes it creates for its own use, and which it won't allow in the
+// names of other logical volumes.
+var reservedVolumeNameParts = []string{
+	"_cdata", "_cmeta", "_corig", "_cpool", "_cvol", "_imeta", "_iorig",
+	"_mimage", "_mlog", "_pmspare", "_rimage", "_rmeta", "_tdata",
+	"_tmeta", "_vdata", "_vorigin", "_wcorig",
+}
+
+// ValidateVolumeName checks that a name is one that lvm will accept for a
+// logical volume.
+func ValidateVolumeName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("empty LV name")
+	case len(name) > MaxVolumeNameLength:
+		return errors.Errorf("LV name %q is longer than %d characters", name, MaxVolumeNameLength)
+	case name == "." || name == "..":
+		return errors.Errorf("LV name %q is not allowed", name)
+	case name[0] == '-':
+		return errors.Errorf("LV name %q starts with a hyphen", name)
+	case strings.HasPrefix(name, "snapshot") || strings.HasPrefix(name, "pvmove"):
+		return errors.Errorf("LV name %q uses a reserved prefix", name)
+	}
+	for _, c := range name {
+		if !validNameCharacter(c) {
+			return errors.Errorf("LV name %q contains invalid character %q", name, c)
+		}
+	}
+	for _, part := range reservedVolumeNameParts {
+		if strings.Contains(name, part) {
+			return errors.Errorf("LV name %q contains reserved string %q", name, part)
+		}
+	}
+	return nil
+}
+
+// ValidateTag checks that a string is one that lvm will accept as a tag.
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return errors.New("empty tag")
+	}
+	if len(tag) > 1024 {
+		return errors.Errorf("tag %q is longer than 1024 characters", tag)
+	}
+	for _, c := range tag {
+		if !validNameCharacter(c) && !strings.ContainsRune("/=!:&#", c) {
+			return errors.Errorf("tag %q contains invalid character %q", tag, c)
+		}
+	}
+	return nil
+}
+
+func validNameCharacter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.ContainsRune("+_.-", c)
+}
+
+// splitTags splits the comma-separated list of tags in a report.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
+
+// VolumeNameForID converts an ID into a volume name using DefaultNamer.  If
+// DefaultNamer can't produce a name for the ID, it returns "layer." followed by
+// the ID, as it always did, so that lvm reports the problem with the name.
+//
+// Deprecated: use DefaultNamer.VolumeName, which returns an error for IDs that
+// can't be used, along with the tags that the volume needs.
+func VolumeNameForID(ID string) string {
+	name, _, err := DefaultNamer.VolumeName(ID)
+	if err != nil {
+		return "layer." + ID
+	}
+	return name
+}
+
+// IDForVolumeName recovers the ID from the name and tags of a logical volume
+// which was named by DefaultNamer.
+func IDForVolumeName(name string, tags ...string) (string, bool) {
+	return DefaultNamer.ID(name, tags...)
+}
+
+func (c *Client) namer() Namer {
+	if c.Namer != nil {
+		return c.Namer
+	}
+	return DefaultNamer
+}
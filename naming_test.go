@@ -0,0 +1,64 @@
+package lvm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrefixNamer(t *testing.T) {
+	namer := PrefixNamer{Prefix: "layer."}
+
+	name, tags, err := namer.VolumeName("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "layer.abc123" || len(tags) != 0 {
+		t.Fatalf("unexpected name %q and tags %v", name, tags)
+	}
+	if id, ok := namer.ID(name, tags...); !ok || id != "abc123" {
+		t.Fatalf("expected to recover ID \"abc123\", got %q", id)
+	}
+	if _, ok := namer.ID("root"); ok {
+		t.Fatalf("expected \"root\" to not be recognized")
+	}
+
+	long := strings.Repeat("0123456789abcdef", 10)
+	name, tags, err = namer.VolumeName(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(name) > MaxVolumeNameLength || len(tags) != 1 || tags[0] != IDTag+long {
+		t.Fatalf("unexpected name %q and tags %v for a long ID", name, tags)
+	}
+	if id, ok := namer.ID(name, tags...); !ok || id != long {
+		t.Fatalf("expected to recover a long ID, got %q", id)
+	}
+
+	for _, id := range []string{"", "a b", "sha256:abc", "x_tmeta"} {
+		if _, _, err = namer.VolumeName(id); err == nil {
+			t.Errorf("expected an error naming a volume for ID %q", id)
+		}
+	}
+}
+
+func TestValidateVolumeName(t *testing.T) {
+	for _, name := range []string{"root", "layer.abc", "a+b_c-d"} {
+		if err := ValidateVolumeName(name); err != nil {
+			t.Errorf("expected %q to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "-x", "snapshot0", "pvmove1", "a/b", "pool_tdata", strings.Repeat("a", 128)} {
+		if err := ValidateVolumeName(name); err == nil {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestVolumeNameForID(t *testing.T) {
+	if name := VolumeNameForID("abc123"); name != "layer.abc123" {
+		t.Errorf("expected \"layer.abc123\", got %q", name)
+	}
+	if name := VolumeNameForID("sha256:abc"); name != "layer.sha256:abc" {
+		t.Errorf("expected an ID which can't be used to be returned unchanged, got %q", name)
+	}
+}
@@ -0,0 +1,162 @@
+package lvm
+
+import (
+	"context"
+
+	"github.com/haircommander/lvm-go/dm"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Conditions of a thin pool which are reported by WatchPoolEvents.
+const (
+	PoolNeedsCheck     = "needs_check"
+	PoolOutOfDataSpace = "out_of_data_space"
+	PoolReadOnly       = "read_only"
+	PoolFail           = "fail"
+)
+
+// PoolEvent describes a change in the state of a thin pool.  If Err is set,
+// watching has failed and no more events will be delivered.
+type PoolEvent struct {
+	VGName   string
+	PoolName string
+	Status   dm.ThinPoolStatus
+	// Set lists the conditions which have started to apply to the pool, and
+	// Cleared lists the ones which no longer do.
+	Set     []string
+	Cleared []string
+	Err     error
+}
+
+// poolConditions returns the set of conditions which apply to a pool with the
+// specified status.
+func poolConditions(s dm.ThinPoolStatus) map[string]bool {
+	return map[string]bool{
+		PoolNeedsCheck:     s.NeedsCheck,
+		PoolOutOfDataSpace: s.OutOfDataSpace,
+		PoolReadOnly:       s.ReadOnly,
+		PoolFail:           s.Fail,
+	}
+}
+
+// poolTransitions compares a pool's status before and after an event, and
+// returns the conditions which started to apply and the ones which stopped.
+func poolTransitions(before, after dm.ThinPoolStatus) (set, cleared []string) {
+	was, is := poolConditions(before), poolConditions(after)
+	for _, condition := range []string{PoolNeedsCheck, PoolOutOfDataSpace, PoolReadOnly, PoolFail} {
+		switch {
+		case is[condition] && !was[condition]:
+			set = append(set, condition)
+		case was[condition] && !is[condition]:
+			cleared = append(cleared, condition)
+		}
+	}
+	return set, cleared
+}
+
+// findThinPoolDevice locates the device-mapper device which holds the
+// thin-pool target for the named pool, and returns it along with its state
+// and the pool's current status.
+func findThinPoolDevice(control *dm.Control, vgname, poolname string) (dm.DeviceInfo, dm.ThinPoolStatus, error) {
+	name := DeviceMapperName(vgname, poolname)
+	for _, candidate := range []string{name + "-tpool", name} {
+		device := dm.Device{Name: candidate}
+		info, err := control.DeviceStatus(device)
+		if err != nil {
+			continue
+		}
+		targets, err := control.TableStatus(device)
+		if err != nil {
+			continue
+		}
+		for _, target := range targets {
+			if target.Type != "thin-pool" {
+				continue
+			}
+			status, err := dm.ParseThinPoolStatus(target.Params)
+			if err != nil {
+				return dm.DeviceInfo{}, dm.ThinPoolStatus{}, err
+			}
+			return info, status, nil
+		}
+	}
+	return dm.DeviceInfo{}, dm.ThinPoolStatus{}, errors.Errorf("no active thin pool %q found", vgname+"/"+poolname)
+}
+
+// WatchPoolEvents watches an active thin pool for changes in whether it needs
+// to be checked, has run out of data space, has switched to read-only mode, or
+// has failed, and delivers a PoolEvent on the returned channel for each such
+// transition.  If any of those conditions already apply when watching starts,
+// the first event reports them as set.  The channel is closed after the context is canceled, which
+// takes effect immediately unless the kernel is too old to support canceling,
+// as described for dm.Control.WaitEventContext.
+func WatchPoolEvents(ctx context.Context, vgname, poolname string) (<-chan PoolEvent, error) {
+	control, err := dm.Open()
+	if err != nil {
+		return nil, err
+	}
+	info, status, err := findThinPoolDevice(control, vgname, poolname)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+	events := make(chan PoolEvent)
+	go func() {
+		defer close(events)
+		defer control.Close()
+		send := func(event PoolEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if set, _ := poolTransitions(dm.ThinPoolStatus{}, status); len(set) > 0 {
+			if !send(PoolEvent{VGName: vgname, PoolName: poolname, Status: status, Set: set}) {
+				return
+			}
+		}
+		device := dm.Device{Name: info.Name}
+		eventNr := info.EventNumber
+		for {
+			next, targets, err := control.WaitEventContext(ctx, device, eventNr)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				send(PoolEvent{VGName: vgname, PoolName: poolname, Err: err})
+				return
+			}
+			eventNr = next.EventNumber
+			logrus.Debugf("thin pool %q reported event %d", vgname+"/"+poolname, eventNr)
+			var updated *dm.ThinPoolStatus
+			for _, target := range targets {
+				if target.Type == "thin-pool" {
+					s, err := dm.ParseThinPoolStatus(target.Params)
+					if err != nil {
+						send(PoolEvent{VGName: vgname, PoolName: poolname, Err: err})
+						return
+					}
+					updated = &s
+					break
+				}
+			}
+			if updated == nil {
+				send(PoolEvent{VGName: vgname, PoolName: poolname, Err: errors.Errorf("thin pool %q is no longer active", vgname+"/"+poolname)})
+				return
+			}
+			event := PoolEvent{VGName: vgname, PoolName: poolname, Status: *updated}
+			event.Set, event.Cleared = poolTransitions(status, *updated)
+			status = *updated
+			if len(event.Set) == 0 && len(event.Cleared) == 0 {
+				continue
+			}
+			if !send(event) {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
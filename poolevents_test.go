@@ -0,0 +1,26 @@
+package lvm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haircommander/lvm-go/dm"
+)
+
+func TestPoolTransitions(t *testing.T) {
+	for _, test := range []struct {
+		before, after dm.ThinPoolStatus
+		set, cleared  []string
+	}{
+		{dm.ThinPoolStatus{}, dm.ThinPoolStatus{}, nil, nil},
+		{dm.ThinPoolStatus{}, dm.ThinPoolStatus{NeedsCheck: true}, []string{PoolNeedsCheck}, nil},
+		{dm.ThinPoolStatus{OutOfDataSpace: true}, dm.ThinPoolStatus{}, nil, []string{PoolOutOfDataSpace}},
+		{dm.ThinPoolStatus{OutOfDataSpace: true}, dm.ThinPoolStatus{OutOfDataSpace: true, ReadOnly: true}, []string{PoolReadOnly}, nil},
+		{dm.ThinPoolStatus{ReadOnly: true, NeedsCheck: true}, dm.ThinPoolStatus{Fail: true, NeedsCheck: true}, []string{PoolFail}, []string{PoolReadOnly}},
+	} {
+		set, cleared := poolTransitions(test.before, test.after)
+		if !reflect.DeepEqual(set, test.set) || !reflect.DeepEqual(cleared, test.cleared) {
+			t.Errorf("%+v -> %+v: expected set %q and cleared %q, got %q and %q", test.before, test.after, test.set, test.cleared, set, cleared)
+		}
+	}
+}
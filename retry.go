@@ -0,0 +1,98 @@
+package lvm
+
+import (
+	"time"
+)
+
+// Backoff describes how long to wait between attempts at running a command.
+type Backoff struct {
+	// Attempts is the maximum number of times to run the command.
+	Attempts int
+	// Initial is how long to wait after the first failure.
+	Initial time.Duration
+	// Max is the longest that we'll wait between attempts.
+	Max time.Duration
+	// Multiplier is how much the wait grows after each failure.  Values
+	// less than 1 are treated as 1.
+	Multiplier float64
+}
+
+// Delay returns how long to wait after the specified attempt, counting from 1,
+// has failed.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		if b.Multiplier > 1 {
+			delay *= b.Multiplier
+		}
+		if b.Max > 0 && delay >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	return time.Duration(delay)
+}
+
+// RetryPolicy describes which failed commands are run again, and how often.
+type RetryPolicy struct {
+	// Backoff controls how many times a command is run and how long to wait
+	// between attempts.
+	Backoff Backoff
+	// Retryable lists the classes of errors for which reporting commands
+	// are retried.
+	Retryable []ErrorClass
+	// RetryableChanges lists the classes of errors for which commands that
+	// modify storage are retried.  Since such a command can fail after it
+	// has made its change, for example with a udev error after creating a
+	// logical volume, only classes of errors which lvm reports before it
+	// changes anything belong here.  Device-busy errors are only retried
+	// for commands which deactivate or remove volumes, even if listed.
+	RetryableChanges []ErrorClass
+}
+
+// DefaultRetryPolicy retries failures which are usually caused by other
+// processes using the same storage at the same time.
+var DefaultRetryPolicy = RetryPolicy{
+	Backoff: Backoff{
+		Attempts:   5,
+		Initial:    100 * time.Millisecond,
+		Max:        2 * time.Second,
+		Multiplier: 2,
+	},
+	Retryable:        []ErrorClass{ErrorLockContention, ErrorDeviceBusy, ErrorUdev},
+	RetryableChanges: []ErrorClass{ErrorLockContention, ErrorDeviceBusy},
+}
+
+// Retries checks if the policy retries a command which failed with the
+// specified error.
+func (p RetryPolicy) Retries(cmd Command, err error) bool {
+	class := Classify(err)
+	retryable := p.Retryable
+	if cmd.Mutating {
+		retryable = p.RetryableChanges
+		if class == ErrorDeviceBusy && !deactivates(cmd) {
+			return false
+		}
+	}
+	for _, r := range retryable {
+		if class == r {
+			return true
+		}
+	}
+	return false
+}
+
+// deactivates checks if a command deactivates or removes volumes, which lvm
+// refuses to do, without changing anything, while they're in use.
+func deactivates(cmd Command) bool {
+	switch cmd.Subcommand() {
+	case "lvremove", "vgremove":
+		return true
+	case "lvchange", "vgchange":
+		for i, arg := range cmd.Args {
+			if arg == "--activate" && i+1 < len(cmd.Args) && cmd.Args[i+1] == "n" {
+				return true
+			}
+		}
+	}
+	return false
+}
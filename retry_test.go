@@ -0,0 +1,108 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if d := b.Delay(i + 1); d != delay {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, delay, d)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		stderr   string
+		exitCode int
+		class    ErrorClass
+	}{
+		{"  Can't get lock for vg\n", 5, ErrorLockContention},
+		{"  Logical volume vg/lv in use.\n", 5, ErrorDeviceBusy},
+		{"  WARNING: Device /dev/sdb not initialized in udev database even after waiting 10000000 microseconds.\n", 5, ErrorUdev},
+		{"  Volume group \"vg\" not found\n", 5, ErrorNotFound},
+		{"  WARNING: Device /dev/sdb not initialized in udev database even after waiting 10000000 microseconds.\n  Volume group \"vg\" not found\n", 5, ErrorNotFound},
+		{"  device-mapper: remove ioctl on vg-lv failed: Device or resource busy\n", 5, ErrorDeviceBusy},
+		{"  Logical volume vg/busy_lv created.\n  Failed to activate: something else\n", 5, ErrorUnknown},
+		{"  Insufficient free space: 256 extents needed, but only 10 available\n", 5, ErrorNoSpace},
+		{"  Invalid argument for --size: 1Q\n", 3, ErrorInvalidArgument},
+		{"  Something else\n", 5, ErrorUnknown},
+	}
+	for _, test := range tests {
+		err := errors.Wrap(&CommandError{Stderr: test.stderr, ExitCode: test.exitCode}, "error running lvm")
+		if class := Classify(err); class != test.class {
+			t.Errorf("expected %q to be classified as %q, got %q", test.stderr, test.class, class)
+		}
+	}
+	if class := Classify(nil); class != ErrorNone {
+		t.Errorf("expected no class for a nil error, got %q", class)
+	}
+	if class := Classify(errors.New("not from a command")); class != ErrorUnknown {
+		t.Errorf("expected an unknown class, got %q", class)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "marker")
+	script := filepath.Join(dir, "lvm")
+	contents := "#!/bin/sh\nif ! test -e " + marker + "; then touch " + marker + "; echo \"  Logical volume vg/lv in use.\" >&2; exit 5; fi\necho '{\"report\": []}'\n"
+	if err = ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{LVMPath: script, Retry: &RetryPolicy{
+		Backoff:   Backoff{Attempts: 2, Initial: time.Millisecond},
+		Retryable: []ErrorClass{ErrorLockContention},
+	}}
+	if _, err = c.GetVolumeGroups("vg"); Classify(err) != ErrorDeviceBusy {
+		t.Fatalf("expected a device-busy error to not be retried, got %v", err)
+	}
+	os.Remove(marker)
+
+	c.Retry.Retryable = append(c.Retry.Retryable, ErrorDeviceBusy)
+	if _, err = c.GetVolumeGroups("vg"); err != nil {
+		t.Fatalf("expected a device-busy error to be retried, got %v", err)
+	}
+}
+
+func TestRetryPolicyChanges(t *testing.T) {
+	policy := DefaultRetryPolicy
+	for _, test := range []struct {
+		args    []string
+		stderr  string
+		retried bool
+	}{
+		{[]string{"lvcreate", "--name", "lv", "vg"}, "  Can't get lock for vg\n", true},
+		// The volume may have been created before udev failed.
+		{[]string{"lvcreate", "--name", "lv", "vg"}, "  WARNING: Device /dev/dm-3 not initialized in udev database even after waiting 10000000 microseconds.\n", false},
+		{[]string{"lvcreate", "--name", "lv", "vg"}, "  device-mapper: reload ioctl on vg-lv failed: Device or resource busy\n", false},
+		{[]string{"lvchange", "--activate", "n", "vg/lv"}, "  Logical volume vg/lv in use.\n", true},
+		{[]string{"lvremove", "--force", "vg/lv"}, "  Logical volume vg/lv in use.\n", true},
+		{[]string{"lvchange", "--activate", "y", "vg/lv"}, "  Logical volume vg/lv in use.\n", false},
+	} {
+		cmd := Command{Path: "lvm", Args: test.args, Mutating: true}
+		err := &CommandError{Stderr: test.stderr, ExitCode: 5}
+		if retried := policy.Retries(cmd, err); retried != test.retried {
+			t.Errorf("%v failing with %q: expected retried=%v, got %v", test.args, test.stderr, test.retried, retried)
+		}
+	}
+	// Reporting commands are retried for udev errors.
+	cmd := Command{Path: "lvm", Args: []string{"fullreport"}}
+	if !policy.Retries(cmd, &CommandError{Stderr: "  WARNING: Device /dev/sdb not initialized in udev database even after waiting 10000000 microseconds.\n", ExitCode: 5}) {
+		t.Error("expected a reporting command to be retried after a udev error")
+	}
+}
@@ -0,0 +1,114 @@
+package lvm
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Size is a size which was given to ParseSize: either a number of bytes, or a
+// percentage of some other quantity.
+type Size struct {
+	Bytes int64
+	// Percent and Of are set for sizes like "50%FREE".  Of is one of
+	// "FREE", "VG", "PVS", or "ORIGIN", as accepted by lvcreate and
+	// lvextend.
+	Percent int
+	Of      string
+}
+
+// IsPercentage checks if the size is relative to another quantity.
+func (s Size) IsPercentage() bool {
+	return s.Of != ""
+}
+
+// String returns the size as either a number of bytes or a percentage like
+// "50%FREE".
+func (s Size) String() string {
+	if s.IsPercentage() {
+		return strconv.Itoa(s.Percent) + "%" + s.Of
+	}
+	return strconv.FormatInt(s.Bytes, 10)
+}
+
+// Args returns the lvcreate or lvextend flags which request the size.
+func (s Size) Args() []string {
+	if s.IsPercentage() {
+		return []string{"--extents", s.String()}
+	}
+	return []string{"--size", s.String() + "b"}
+}
+
+// sizeMultipliers maps suffixes to the number of bytes they stand for.  As
+// with lvm's own options, single-letter suffixes are powers of 1024, whichever
+// case they are written in.  Suffixes like "GB" are powers of 1000, and
+// suffixes like "GiB" are powers of 1024.
+var sizeMultipliers = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"m":   1 << 20,
+	"g":   1 << 30,
+	"t":   1 << 40,
+	"p":   1 << 50,
+	"e":   1 << 60,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+	"eib": 1 << 60,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"eb":  1e18,
+}
+
+// percentTargets are the quantities which a size can be a percentage of.
+var percentTargets = []string{"FREE", "VG", "PVS", "ORIGIN"}
+
+// ParseSize parses a size like "10G", "512MiB", "1.5TB", "4096", or "50%FREE".
+// A number without a suffix is a number of bytes.
+func ParseSize(s string) (Size, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "%"); i >= 0 {
+		percent, err := strconv.Atoi(s[:i])
+		if err != nil || percent <= 0 {
+			return Size{}, errors.Errorf("invalid percentage in size %q", s)
+		}
+		of := strings.ToUpper(s[i+1:])
+		for _, target := range percentTargets {
+			if of == target {
+				return Size{Percent: percent, Of: of}, nil
+			}
+		}
+		return Size{}, errors.Errorf("invalid size %q: expected a percentage of one of %v", s, percentTargets)
+	}
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	number, suffix := s[:end], strings.ToLower(strings.TrimSpace(s[end:]))
+	multiplier, ok := sizeMultipliers[suffix]
+	if !ok || number == "" {
+		return Size{}, errors.Errorf("invalid size %q", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return Size{}, errors.Wrapf(err, "invalid size %q", s)
+	}
+	bytes := value * multiplier
+	if bytes >= math.MaxInt64 {
+		return Size{}, errors.Errorf("size %q is too large", s)
+	}
+	if bytes != math.Trunc(bytes) {
+		return Size{}, errors.Errorf("size %q is not a whole number of bytes", s)
+	}
+	return Size{Bytes: int64(bytes)}, nil
+}
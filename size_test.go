@@ -0,0 +1,53 @@
+package lvm
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected Size
+	}{
+		{"4096", Size{Bytes: 4096}},
+		{"4096b", Size{Bytes: 4096}},
+		{"10G", Size{Bytes: 10 << 30}},
+		{"10g", Size{Bytes: 10 << 30}},
+		{"512MiB", Size{Bytes: 512 << 20}},
+		{"1.5TB", Size{Bytes: 1500000000000}},
+		{"0.5k", Size{Bytes: 512}},
+		{"50%FREE", Size{Percent: 50, Of: "FREE"}},
+		{"100%vg", Size{Percent: 100, Of: "VG"}},
+	} {
+		size, err := ParseSize(test.s)
+		if err != nil {
+			t.Errorf("ParseSize(%q): %v", test.s, err)
+			continue
+		}
+		if size != test.expected {
+			t.Errorf("ParseSize(%q): expected %+v, got %+v", test.s, test.expected, size)
+		}
+	}
+	for _, s := range []string{"", "G", "10X", "1.2.3G", "0.3b", "50%", "50%DISK", "-5%FREE", "16E", "10 GiB extra"} {
+		if size, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q): expected an error, got %+v", s, size)
+		}
+	}
+}
+
+func TestSizeArgs(t *testing.T) {
+	size, err := ParseSize("1K")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := size.Args(); len(args) != 2 || args[0] != "--size" || args[1] != "1024b" {
+		t.Errorf("unexpected arguments %q", args)
+	}
+	size, err = ParseSize("90%FREE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := size.Args(); len(args) != 2 || args[0] != "--extents" || args[1] != "90%FREE" {
+		t.Errorf("unexpected arguments %q", args)
+	}
+}
@@ -0,0 +1,181 @@
+package lvm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SplitVolumeGroup is a wrapper around DefaultClient.SplitVolumeGroup.
+func SplitVolumeGroup(src, dst string, device ...string) (Report, error) {
+	return DefaultClient.SplitVolumeGroup(src, dst, device...)
+}
+
+// SplitVolumeGroup moves the specified physical volumes, and the logical
+// volumes which use them, from the src volume group into the dst volume group,
+// creating dst if it doesn't already exist.  The logical volumes which would
+// be moved must be inactive.  It returns reports on both volume groups.
+func (c *Client) SplitVolumeGroup(src, dst string, device ...string) (Report, error) {
+	if len(device) == 0 {
+		return Report{}, errors.Errorf("no physical volumes specified for splitting %q", src)
+	}
+	report, err := c.getFullReport(src, true)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error reading information about volume group %q", src)
+	}
+	pvs := map[string]bool{}
+	for _, d := range device {
+		pvs[d] = true
+	}
+	seeds := map[string]bool{}
+	for _, entry := range report.Reports {
+		for _, seg := range entry.Segs {
+			for _, r := range strings.Fields(seg.PERanges) {
+				if i := strings.LastIndex(r, ":"); i != -1 && pvs[r[:i]] {
+					seeds[seg.LVUUID] = true
+				}
+			}
+		}
+	}
+	if err = checkInactive(src, report, seeds); err != nil {
+		return Report{}, err
+	}
+	_, err = c.mutate([]string{src, dst}, append([]string{"vgsplit", src, dst}, device...)...)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error running \"lvm vgsplit\" for %v", device)
+	}
+	return c.getVolumeGroupReports(src, dst)
+}
+
+// SplitVolumeGroupByLogicalVolume is a wrapper around DefaultClient.SplitVolumeGroupByLogicalVolume.
+func SplitVolumeGroupByLogicalVolume(src, dst, volume string) (Report, error) {
+	return DefaultClient.SplitVolumeGroupByLogicalVolume(src, dst, volume)
+}
+
+// SplitVolumeGroupByLogicalVolume moves the physical volumes which are used by
+// the specified logical volume from the src volume group into the dst volume
+// group, creating dst if it doesn't already exist.  The logical volumes which
+// would be moved must be inactive.  It returns reports on both volume groups.
+func (c *Client) SplitVolumeGroupByLogicalVolume(src, dst, volume string) (Report, error) {
+	report, err := c.getFullReport(src, true)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error reading information about volume group %q", src)
+	}
+	seeds := map[string]bool{}
+	for _, entry := range report.Reports {
+		for _, lv := range entry.LVs {
+			if lv.Name == volume {
+				seeds[lv.UUID] = true
+			}
+		}
+	}
+	if len(seeds) == 0 {
+		return Report{}, errors.Errorf("no LV named %q found", src+"/"+volume)
+	}
+	if err = checkInactive(src, report, seeds); err != nil {
+		return Report{}, err
+	}
+	_, err = c.mutate([]string{src, dst}, "vgsplit", "--name", volume, src, dst)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error running \"lvm vgsplit\" for %q", src+"/"+volume)
+	}
+	return c.getVolumeGroupReports(src, dst)
+}
+
+// MergeVolumeGroups is a wrapper around DefaultClient.MergeVolumeGroups.
+func MergeVolumeGroups(dst, src string) (Report, error) {
+	return DefaultClient.MergeVolumeGroups(dst, src)
+}
+
+// MergeVolumeGroups merges the src volume group into the dst volume group.
+// The logical volumes in src must be inactive.  It returns a report on the
+// resulting volume group.
+func (c *Client) MergeVolumeGroups(dst, src string) (Report, error) {
+	report, err := c.GetLogicalVolumes(src, "")
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error reading information about volume group %q", src)
+	}
+	active := []string{}
+	for _, entry := range report.Reports {
+		for _, lv := range entry.LVs {
+			if lv.IsActive() {
+				active = append(active, lv.Name)
+			}
+		}
+	}
+	if len(active) > 0 {
+		return Report{}, errors.Errorf("volume group %q has active logical volumes %v", src, active)
+	}
+	_, err = c.mutate([]string{dst, src}, "vgmerge", dst, src)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "error running \"lvm vgmerge\" for %q into %q", src, dst)
+	}
+	return c.GetVolumeGroups(dst)
+}
+
+// checkInactive verifies that none of the logical volumes with the specified
+// UUIDs, and none of the logical volumes which are tied to them because they
+// are layers or snapshots of one another or share a thin pool, are active.
+func checkInactive(vgname string, report ReportFull, uuids map[string]bool) error {
+	lvs := []ReportLVFull{}
+	for _, entry := range report.Reports {
+		lvs = append(lvs, entry.LVs...)
+	}
+	affected := map[string]bool{}
+	for _, lv := range lvs {
+		if uuids[lv.UUID] {
+			affected[stripBrackets(lv.Name)] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, lv := range lvs {
+			name := stripBrackets(lv.Name)
+			related := []string{stripBrackets(lv.Parent), stripBrackets(lv.PoolLV), stripBrackets(lv.Origin)}
+			for _, r := range related {
+				if r == "" {
+					continue
+				}
+				if affected[name] && !affected[r] {
+					affected[r] = true
+					changed = true
+				}
+				if affected[r] && !affected[name] {
+					affected[name] = true
+					changed = true
+				}
+			}
+		}
+	}
+	active := []string{}
+	for _, lv := range lvs {
+		if affected[stripBrackets(lv.Name)] && lv.IsActive() {
+			active = append(active, stripBrackets(lv.Name))
+		}
+	}
+	if len(active) > 0 {
+		sort.Strings(active)
+		return errors.Errorf("logical volumes %v in volume group %q are active", active, vgname)
+	}
+	return nil
+}
+
+// getVolumeGroupReports returns reports on each of the specified volume groups.
+func (c *Client) getVolumeGroupReports(vgname ...string) (Report, error) {
+	report := Report{}
+	for _, name := range vgname {
+		r, err := c.GetVolumeGroups(name)
+		if err != nil {
+			return Report{}, errors.WithStack(err)
+		}
+		report.Reports = append(report.Reports, r.Reports...)
+	}
+	return report, nil
+}
+
+// stripBrackets removes the brackets which lvm places around the names of
+// internal logical volumes.
+func stripBrackets(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+}
@@ -0,0 +1,125 @@
+package lvm
+
+import (
+	"strings"
+	"testing"
+)
+
+// splitReport describes a volume group with a plain volume on /dev/sdb, an
+// active volume on /dev/sdb1, a thin pool on /dev/sdc with an active thin
+// volume in it, and an active snapshot of a volume on /dev/sdd.
+const splitReport = `{"report": [{"vg": [{"vg_name": "vg"}], "lv": [
+	{"lv_name": "data", "lv_uuid": "data-uuid", "lv_attr": "-wi-------"},
+	{"lv_name": "other", "lv_uuid": "other-uuid", "lv_attr": "-wi-a-----"},
+	{"lv_name": "pool", "lv_uuid": "pool-uuid", "lv_attr": "twi---tz--"},
+	{"lv_name": "[pool_tdata]", "lv_uuid": "tdata-uuid", "lv_attr": "Twi-------", "lv_parent": "pool"},
+	{"lv_name": "thin", "lv_uuid": "thin-uuid", "lv_attr": "Vwi-a-tz--", "pool_lv": "pool"},
+	{"lv_name": "base", "lv_uuid": "base-uuid", "lv_attr": "owi-------"},
+	{"lv_name": "snap", "lv_uuid": "snap-uuid", "lv_attr": "swi-a-s---", "origin": "base"}
+], "seg": [
+	{"lv_uuid": "data-uuid", "segtype": "linear", "seg_pe_ranges": "/dev/sdb:0-99"},
+	{"lv_uuid": "other-uuid", "segtype": "linear", "seg_pe_ranges": "/dev/sdb1:0-9"},
+	{"lv_uuid": "tdata-uuid", "segtype": "linear", "seg_pe_ranges": "/dev/sdc:0-99"},
+	{"lv_uuid": "base-uuid", "segtype": "linear", "seg_pe_ranges": "/dev/sdd:0-49"}
+]}]}`
+
+// mutatingCommands returns the commands which a RecordingExecutor recorded
+// without running them, without the path of the lvm binary.
+func mutatingCommands(recorder *RecordingExecutor) []string {
+	commands := []string{}
+	for _, cmd := range recorder.Commands() {
+		if cmd.Mutating {
+			commands = append(commands, strings.Join(cmd.Args, " "))
+		}
+	}
+	return commands
+}
+
+func TestSplitVolumeGroup(t *testing.T) {
+	script, _ := fakeLVM(t, splitReport)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+
+	// Only LVs on exactly the named PV are moved, so the active volume on
+	// /dev/sdb1 doesn't get in the way.
+	if _, err := c.SplitVolumeGroup("vg", "new", "/dev/sdb"); err != nil {
+		t.Fatal(err)
+	}
+	if commands := mutatingCommands(recorder); len(commands) != 1 || commands[0] != "vgsplit vg new /dev/sdb" {
+		t.Fatalf("unexpected commands %q", commands)
+	}
+
+	for _, test := range []struct {
+		device string
+		active string
+	}{
+		{"/dev/sdb1", "[other]"},
+		// The pool's data volume ties the pool and its thin volumes to
+		// the PV.
+		{"/dev/sdc", "[thin]"},
+		// Snapshots move along with their origins.
+		{"/dev/sdd", "[snap]"},
+	} {
+		recorder.Reset()
+		_, err := c.SplitVolumeGroup("vg", "new", test.device)
+		if err == nil || !strings.Contains(err.Error(), test.active) {
+			t.Errorf("expected splitting off %q to be refused because of %s, got %v", test.device, test.active, err)
+		}
+		if commands := mutatingCommands(recorder); len(commands) != 0 {
+			t.Errorf("expected no changes when splitting off %q, got %q", test.device, commands)
+		}
+	}
+
+	if _, err := c.SplitVolumeGroup("vg", "new"); err == nil {
+		t.Fatal("expected an error when no PVs are named")
+	}
+}
+
+func TestSplitVolumeGroupByLogicalVolume(t *testing.T) {
+	script, _ := fakeLVM(t, splitReport)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+
+	if _, err := c.SplitVolumeGroupByLogicalVolume("vg", "new", "data"); err != nil {
+		t.Fatal(err)
+	}
+	if commands := mutatingCommands(recorder); len(commands) != 1 || commands[0] != "vgsplit --name data vg new" {
+		t.Fatalf("unexpected commands %q", commands)
+	}
+
+	recorder.Reset()
+	if _, err := c.SplitVolumeGroupByLogicalVolume("vg", "new", "pool"); err == nil || !strings.Contains(err.Error(), "[thin]") {
+		t.Fatalf("expected splitting off a pool with an active thin volume to be refused, got %v", err)
+	}
+	if _, err := c.SplitVolumeGroupByLogicalVolume("vg", "new", "base"); err == nil || !strings.Contains(err.Error(), "[snap]") {
+		t.Fatalf("expected splitting off an origin with an active snapshot to be refused, got %v", err)
+	}
+	if _, err := c.SplitVolumeGroupByLogicalVolume("vg", "new", "missing"); err == nil {
+		t.Fatal("expected an error splitting off a volume which doesn't exist")
+	}
+	if commands := mutatingCommands(recorder); len(commands) != 0 {
+		t.Fatalf("expected no changes, got %q", commands)
+	}
+}
+
+func TestMergeVolumeGroups(t *testing.T) {
+	script, _ := fakeLVM(t, `{"report": [{"lv": [{"lv_name": "data", "vg_name": "src", "lv_attr": "-wi-------"}]}]}`)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+	if _, err := c.MergeVolumeGroups("dst", "src"); err != nil {
+		t.Fatal(err)
+	}
+	if commands := mutatingCommands(recorder); len(commands) != 1 || commands[0] != "vgmerge dst src" {
+		t.Fatalf("unexpected commands %q", commands)
+	}
+
+	script, _ = fakeLVM(t, `{"report": [{"lv": [{"lv_name": "data", "vg_name": "src", "lv_attr": "-wi-a-----"}]}]}`)
+	recorder.Reset()
+	c = &Client{LVMPath: script, Executor: recorder}
+	if _, err := c.MergeVolumeGroups("dst", "src"); err == nil || !strings.Contains(err.Error(), "[data]") {
+		t.Fatalf("expected merging a volume group with an active volume to be refused, got %v", err)
+	}
+	if commands := mutatingCommands(recorder); len(commands) != 0 {
+		t.Fatalf("expected no changes, got %q", commands)
+	}
+}
@@ -0,0 +1,286 @@
+package lvm
+
+import (
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ThinCheckPath is the path to the "thin_check" command.
+	ThinCheckPath string
+)
+
+func init() {
+	if p, err := exec.LookPath("thin_check"); err == nil {
+		ThinCheckPath = p
+	}
+}
+
+// ThinPoolCheckReport is the result of CheckThinPool.
+type ThinPoolCheckReport struct {
+	VGName   string
+	PoolName string
+	// Passed is true if thin_check found no problems.
+	Passed bool
+	// DryRun is set if thin_check was only recorded by a dry run, in which
+	// case Passed is false.
+	DryRun bool
+	// Output is what thin_check printed.
+	Output string
+	// Steps lists the changes which were made to the volume group, in order.
+	Steps []string
+}
+
+// ThinPoolRepairReport is the result of RepairThinPool.
+type ThinPoolRepairReport struct {
+	VGName   string
+	PoolName string
+	// BackupLV is the logical volume which holds the pool's original
+	// metadata, which can be removed once the repaired pool is known to be
+	// working.
+	BackupLV string
+	// SpareUsed is true if an existing metadata spare was used for the
+	// repaired metadata.  A new spare is created if there is space for it.
+	SpareUsed bool
+	// TransactionIDBefore and TransactionIDAfter are the pool's transaction
+	// IDs, as recorded in the volume group's metadata.
+	TransactionIDBefore string
+	TransactionIDAfter  string
+	// Output is what lvm printed.
+	Output string
+}
+
+// thinPoolState is what CheckThinPool and RepairThinPool need to know about a
+// thin pool and the volume group that it's in.
+type thinPoolState struct {
+	pool          ReportLVFull
+	poolSeg       ReportSegFull
+	volumes       map[string]ReportLVFull
+	free          int64
+	spare         string
+	metadataSize  int64
+	transactionID string
+	// active lists the pool's thin volumes which are active, followed by
+	// the pool itself if it is.
+	active []string
+}
+
+// readThinPoolState reads information about a thin pool and the volumes in it.
+func (c *Client) readThinPoolState(vgname, poolname string) (thinPoolState, error) {
+	state := thinPoolState{volumes: map[string]ReportLVFull{}}
+	report, err := c.getFullReport(vgname, true)
+	if err != nil {
+		return state, errors.Wrapf(err, "error reading information about volume group %q", vgname)
+	}
+	segs := map[string]ReportSegFull{}
+	for _, entry := range report.Reports {
+		if !entryInVolumeGroup(entry, vgname) {
+			continue
+		}
+		for _, vg := range entry.VGs {
+			state.free = vg.Free
+		}
+		for _, lv := range entry.LVs {
+			state.volumes[stripBrackets(lv.Name)] = lv
+		}
+		for _, seg := range entry.Segs {
+			segs[seg.LVUUID] = seg
+		}
+	}
+	pool, ok := state.volumes[poolname]
+	if !ok {
+		return state, errors.Errorf("no LV named %q found", vgname+"/"+poolname)
+	}
+	if !strings.HasPrefix(pool.Attributes, "t") {
+		return state, errors.Errorf("LV %q is not a thin pool", vgname+"/"+poolname)
+	}
+	state.pool = pool
+	state.poolSeg = segs[pool.UUID]
+	state.transactionID = state.poolSeg.TransactionID
+	if state.metadataSize, err = strconv.ParseInt(pool.MetadataSize, 10, 64); err != nil || state.metadataSize <= 0 {
+		return state, errors.Errorf("unable to determine metadata size of thin pool %q", vgname+"/"+poolname)
+	}
+	for name, lv := range state.volumes {
+		if stripBrackets(lv.PoolLV) == poolname && lv.IsActive() {
+			state.active = append(state.active, name)
+		}
+		if strings.Contains(name, "_pmspare") {
+			state.spare = name
+		}
+	}
+	sort.Strings(state.active)
+	if pool.IsActive() {
+		state.active = append(state.active, poolname)
+	}
+	return state, nil
+}
+
+// thinPoolStep runs an lvm command as one of the steps of checking a thin
+// pool, and adds its description to the report.
+func (c *Client) thinPoolStep(report *ThinPoolCheckReport, description string, args ...string) error {
+	if _, err := c.mutate([]string{report.VGName}, args...); err != nil {
+		return errors.Wrapf(err, "error running \"lvm %s\" to %s", args[0], description)
+	}
+	report.Steps = append(report.Steps, description)
+	return nil
+}
+
+// CheckThinPool is a wrapper around DefaultClient.CheckThinPool.
+func CheckThinPool(vgname, poolname string) (ThinPoolCheckReport, error) {
+	return DefaultClient.CheckThinPool(vgname, poolname)
+}
+
+// CheckThinPool checks the metadata of a thin pool.  If the pool or any of its
+// thin volumes are active, they are deactivated first, and reactivated
+// afterward, so none of them may be in use.  The pool's metadata is swapped
+// into a temporary logical volume, which requires that the volume group have
+// enough free space for it, checked with thin_check, and swapped back.  An
+// error is returned if the check couldn't be run; problems that the check
+// finds are described in the report.
+func (c *Client) CheckThinPool(vgname, poolname string) (ThinPoolCheckReport, error) {
+	report := ThinPoolCheckReport{VGName: vgname, PoolName: poolname}
+	if ThinCheckPath == "" {
+		return report, errors.New("thin_check command not found")
+	}
+	state, err := c.readThinPoolState(vgname, poolname)
+	if err != nil {
+		return report, err
+	}
+	if state.free < state.metadataSize {
+		return report, errors.Errorf("volume group %q needs %d free bytes to check thin pool %q, but has %d", vgname, state.metadataSize, poolname, state.free)
+	}
+	deactivated := []string{}
+	reactivate := func() {
+		for i := len(deactivated) - 1; i >= 0; i-- {
+			lv := vgname + "/" + deactivated[i]
+			if err := c.thinPoolStep(&report, "reactivate "+lv, "lvchange", "--activate", "y", lv); err != nil {
+				logrus.Errorf("error reactivating %q: %v", lv, err)
+			}
+		}
+	}
+	for _, name := range state.active {
+		lv := vgname + "/" + name
+		if err = c.thinPoolStep(&report, "deactivate "+lv, "lvchange", "--activate", "n", lv); err != nil {
+			reactivate()
+			return report, err
+		}
+		deactivated = append(deactivated, name)
+	}
+	err = c.checkThinPoolMetadata(&report, state)
+	reactivate()
+	return report, err
+}
+
+// checkThinPoolMetadata swaps an inactive thin pool's metadata into a
+// temporary logical volume, runs thin_check on it, and swaps it back.
+func (c *Client) checkThinPoolMetadata(report *ThinPoolCheckReport, state thinPoolState) error {
+	vgname, poolname := report.VGName, report.PoolName
+	temporary := poolname + "_check"
+	for i := 0; ; i++ {
+		if _, exists := state.volumes[temporary]; !exists {
+			break
+		}
+		temporary = poolname + "_check" + strconv.Itoa(i)
+	}
+	step := func(description string, args ...string) error {
+		return c.thinPoolStep(report, description, args...)
+	}
+
+	err := step("create temporary LV "+vgname+"/"+temporary, "lvcreate", "--activate", "n", "--zero", "n", "--size", strconv.FormatInt(state.metadataSize, 10)+"b", "--name", temporary, vgname)
+	if err != nil {
+		return err
+	}
+	if err = step("swap metadata of "+vgname+"/"+poolname+" into "+vgname+"/"+temporary, "lvconvert", "--yes", "--thinpool", vgname+"/"+poolname, "--poolmetadata", vgname+"/"+temporary); err != nil {
+		if removeErr := step("remove temporary LV "+vgname+"/"+temporary, "lvremove", "--force", vgname+"/"+temporary); removeErr != nil {
+			logrus.Errorf("error removing temporary LV %q: %v", vgname+"/"+temporary, removeErr)
+		}
+		return err
+	}
+	// From here on, the pool's metadata is in the temporary volume, so
+	// put it back no matter how the check goes.
+	restore := func() error {
+		if err := step("deactivate "+vgname+"/"+temporary, "lvchange", "--activate", "n", vgname+"/"+temporary); err != nil {
+			return err
+		}
+		if err := step("swap metadata back into "+vgname+"/"+poolname, "lvconvert", "--yes", "--thinpool", vgname+"/"+poolname, "--poolmetadata", vgname+"/"+temporary); err != nil {
+			return err
+		}
+		return step("remove temporary LV "+vgname+"/"+temporary, "lvremove", "--force", vgname+"/"+temporary)
+	}
+	if err = step("activate "+vgname+"/"+temporary, "lvchange", "--activate", "y", "--ignoreactivationskip", vgname+"/"+temporary); err != nil {
+		if restoreErr := restore(); restoreErr != nil {
+			logrus.Errorf("error restoring metadata of thin pool %q: %v", vgname+"/"+poolname, restoreErr)
+		}
+		return err
+	}
+	device := "/dev/mapper/" + DeviceMapperName(vgname, temporary)
+	if c.dryRun() {
+		// The temporary volume doesn't exist in a dry run, so there is
+		// nothing to check, and the command is only recorded.
+		_, err = c.run(Command{Path: ThinCheckPath, Args: []string{device}, Mutating: true})
+		report.DryRun = true
+	} else {
+		var output string
+		output, err = c.run(Command{Path: ThinCheckPath, Args: []string{device}})
+		report.Output = output
+		report.Passed = err == nil
+		if err != nil {
+			if cmdErr, ok := errors.Cause(err).(*CommandError); ok {
+				report.Output += cmdErr.Stderr
+			} else {
+				report.Output += err.Error()
+			}
+		}
+	}
+	if err = restore(); err != nil {
+		return errors.Wrapf(err, "error restoring metadata of thin pool %q, which is in %q", vgname+"/"+poolname, vgname+"/"+temporary)
+	}
+	return nil
+}
+
+// RepairThinPool is a wrapper around DefaultClient.RepairThinPool.
+func RepairThinPool(vgname, poolname string) (ThinPoolRepairReport, error) {
+	return DefaultClient.RepairThinPool(vgname, poolname)
+}
+
+// RepairThinPool repairs the metadata of an inactive thin pool using
+// "lvconvert --repair".  Neither the pool nor any of its thin volumes may be
+// active.  The repaired metadata is written to the volume group's pool
+// metadata spare, or if it doesn't have one, to space which is allocated for
+// it, and the original metadata is kept in a new logical volume.
+func (c *Client) RepairThinPool(vgname, poolname string) (ThinPoolRepairReport, error) {
+	report := ThinPoolRepairReport{VGName: vgname, PoolName: poolname}
+	before, err := c.readThinPoolState(vgname, poolname)
+	if err != nil {
+		return report, err
+	}
+	if len(before.active) > 0 {
+		return report, errors.Errorf("thin pool %q has active volumes %v", vgname+"/"+poolname, before.active)
+	}
+	report.TransactionIDBefore = before.transactionID
+	report.SpareUsed = before.spare != ""
+	if before.spare == "" && before.free < before.metadataSize {
+		return report, errors.Errorf("volume group %q has no pool metadata spare, and needs %d free bytes to repair thin pool %q, but has %d", vgname, before.metadataSize, poolname, before.free)
+	}
+	output, err := c.mutate([]string{vgname}, "lvconvert", "--yes", "--repair", vgname+"/"+poolname)
+	report.Output = output
+	if err != nil {
+		return report, errors.Wrapf(err, "error running \"lvm lvconvert --repair\" for %q", vgname+"/"+poolname)
+	}
+	after, err := c.readThinPoolState(vgname, poolname)
+	if err != nil {
+		return report, errors.Wrapf(err, "error reading state of repaired thin pool %q", vgname+"/"+poolname)
+	}
+	report.TransactionIDAfter = after.transactionID
+	for name := range after.volumes {
+		if _, existed := before.volumes[name]; !existed && strings.HasPrefix(name, poolname+"_meta") {
+			report.BackupLV = name
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,128 @@
+package lvm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// failingExecutor fails commands with a particular subcommand, and runs the
+// rest with another Executor.
+type failingExecutor struct {
+	Executor
+	fail string
+}
+
+func (f failingExecutor) Run(cmd Command) (string, error) {
+	if cmd.Subcommand() == f.fail {
+		return "", errors.Errorf("%s failed", f.fail)
+	}
+	return f.Executor.Run(cmd)
+}
+
+const thinPoolReport = `{"report": [{"vg": [{"vg_name": "vg", "vg_free": "1073741824"}], "lv": [
+	{"lv_name": "pool", "lv_uuid": "pool-uuid", "lv_attr": "twi---tz--", "lv_metadata_size": "4194304"},
+	{"lv_name": "thin", "lv_uuid": "thin-uuid", "lv_attr": "Vwi---tz--", "pool_lv": "pool"},
+	{"lv_name": "active", "lv_uuid": "active-uuid", "lv_attr": "Vwi-a-tz--", "pool_lv": "pool2"},
+	{"lv_name": "[lvol0_pmspare]", "lv_uuid": "spare-uuid", "lv_attr": "ewi-------"}
+], "seg": [
+	{"lv_uuid": "pool-uuid", "segtype": "thin-pool", "transaction_id": "7"}
+]}]}`
+
+func TestCheckThinPool(t *testing.T) {
+	script, _ := fakeLVM(t, thinPoolReport)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+	saved := ThinCheckPath
+	ThinCheckPath = "true"
+	defer func() { ThinCheckPath = saved }()
+
+	if _, err := c.CheckThinPool("vg", "thin"); err == nil {
+		t.Fatal("expected an error checking a volume which is not a thin pool")
+	}
+	recorder.Reset()
+	report, err := c.CheckThinPool("vg", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed || !report.DryRun || len(report.Steps) != 6 {
+		t.Fatalf("unexpected report for a dry run %+v", report)
+	}
+	subcommands := []string{}
+	for _, cmd := range recorder.Commands() {
+		subcommands = append(subcommands, cmd.Subcommand())
+	}
+	expected := "fullreport lvcreate lvconvert lvchange true lvchange lvconvert lvremove"
+	if strings.Join(subcommands, " ") != expected {
+		t.Fatalf("expected commands %q, got %q", expected, strings.Join(subcommands, " "))
+	}
+
+	// If the metadata can't be swapped out, the temporary volume is
+	// removed.
+	recorder.Reset()
+	c.Executor = failingExecutor{Executor: recorder, fail: "lvconvert"}
+	if _, err = c.CheckThinPool("vg", "pool"); err == nil {
+		t.Fatal("expected an error when the metadata can't be swapped")
+	}
+	subcommands = []string{}
+	for _, cmd := range recorder.Commands() {
+		subcommands = append(subcommands, cmd.Subcommand())
+	}
+	expected = "fullreport lvcreate lvremove"
+	if strings.Join(subcommands, " ") != expected {
+		t.Fatalf("expected commands %q, got %q", expected, strings.Join(subcommands, " "))
+	}
+
+	// Outside of a dry run, thin_check's verdict is reported.
+	c = &Client{LVMPath: script}
+	if report, err = c.CheckThinPool("vg", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed || report.DryRun {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// Active volumes are deactivated first and reactivated afterward.
+	active := strings.Replace(thinPoolReport, `"twi---tz--"`, `"twi-a-tz--"`, 1)
+	active = strings.Replace(active, `"pool_lv": "pool2"`, `"pool_lv": "pool"`, 1)
+	script, _ = fakeLVM(t, active)
+	recorder.Reset()
+	c = &Client{LVMPath: script, Executor: recorder}
+	if report, err = c.CheckThinPool("vg", "pool"); err != nil {
+		t.Fatal(err)
+	}
+	commands := recorder.Commands()
+	changes := []string{}
+	for _, cmd := range commands {
+		if cmd.Subcommand() == "lvchange" {
+			changes = append(changes, strings.Join(cmd.Args[1:], " "))
+		}
+	}
+	expected = "--activate n vg/active,--activate n vg/pool,--activate y --ignoreactivationskip vg/pool_check,--activate n vg/pool_check,--activate y vg/pool,--activate y vg/active"
+	if strings.Join(changes, ",") != expected {
+		t.Fatalf("expected activation changes %q, got %q", expected, strings.Join(changes, ","))
+	}
+	if len(report.Steps) != 10 || report.Steps[0] != "deactivate vg/active" || report.Steps[9] != "reactivate vg/active" {
+		t.Fatalf("unexpected steps %q", report.Steps)
+	}
+}
+
+func TestRepairThinPool(t *testing.T) {
+	script, _ := fakeLVM(t, thinPoolReport)
+	c := &Client{LVMPath: script, Executor: &RecordingExecutor{}}
+	report, err := c.RepairThinPool("vg", "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.SpareUsed || report.TransactionIDBefore != "7" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	active := strings.Replace(thinPoolReport, `"pool_lv": "pool2"`, `"pool_lv": "pool"`, 1)
+	script, _ = fakeLVM(t, active)
+	c = &Client{LVMPath: script, Executor: &RecordingExecutor{}}
+	if _, err = c.RepairThinPool("vg", "pool"); err == nil || !strings.Contains(err.Error(), "active") {
+		t.Fatalf("expected an error repairing a pool with active volumes, got %v", err)
+	}
+}
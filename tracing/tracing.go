@@ -0,0 +1,56 @@
+// Package tracing provides an lvm.CommandHook which records each command that
+// is run as an OpenTelemetry span.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	lvm "github.com/haircommander/lvm-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Hook is an lvm.CommandHook which starts a span for each command.
+type Hook struct {
+	Tracer trace.Tracer
+	// Context, if set, is called to obtain the context in which spans are
+	// started, so that they can be made children of a caller's span.
+	Context func() context.Context
+}
+
+// NewHook returns a Hook which starts spans using the specified tracer.
+func NewHook(tracer trace.Tracer) *Hook {
+	return &Hook{Tracer: tracer}
+}
+
+// CommandStarted starts a span for a command.
+func (h *Hook) CommandStarted(cmd lvm.Command) func(err error) {
+	ctx := context.Background()
+	if h.Context != nil {
+		ctx = h.Context()
+	}
+	subcommand := cmd.Subcommand()
+	_, span := h.Tracer.Start(ctx, "lvm "+subcommand, trace.WithSpanKind(trace.SpanKindClient))
+	span.SetAttributes(
+		attribute.String("lvm.subcommand", subcommand),
+		attribute.StringSlice("lvm.args", cmd.Args),
+		attribute.Bool("lvm.mutating", cmd.Mutating),
+	)
+	if len(cmd.VGNames) > 0 {
+		span.SetAttributes(attribute.String("lvm.vg", strings.Join(cmd.VGNames, ",")))
+	}
+	if lv := cmd.LVName(); lv != "" {
+		span.SetAttributes(attribute.String("lvm.lv", lv))
+	}
+	return func(err error) {
+		span.SetAttributes(attribute.Int("lvm.exit_code", lvm.ExitCode(err)))
+		if err != nil {
+			span.SetAttributes(attribute.String("lvm.error_class", string(lvm.Classify(err))))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
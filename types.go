@@ -1,5 +1,9 @@
 package lvm
 
+import (
+	"time"
+)
+
 // ReportLoopback represents information about a configured loopback device,
 // produced by the "losetup --list" command.
 type ReportLoopback struct {
@@ -122,6 +126,12 @@ type ReportLVCommon struct {
 	ConvertLV       string `json:"convert_lv"`
 }
 
+// IsActive checks the state field of the logical volume's attributes to see if
+// it is currently active.
+func (lv ReportLVCommon) IsActive() bool {
+	return len(lv.Attributes) > 4 && lv.Attributes[4] != '-'
+}
+
 // ReportLV represents the information about a logical volume that is produced
 // by the "lvm lvs" command.
 type ReportLV struct {
@@ -219,6 +229,36 @@ type ReportLVFull struct {
 	CheckNeeded         string `json:"lv_check_needed"`
 }
 
+// ReportSegFull represents the information about a segment of a logical
+// volume that is produced by the "lvm fullreport" command.
+type ReportSegFull struct {
+	Type             string `json:"segtype"`
+	Stripes          int64  `json:"stripes,string"`
+	StripeSize       int64  `json:"stripe_size,string"`
+	RegionSize       int64  `json:"region_size,string"`
+	ChunkSize        int64  `json:"chunk_size,string"`
+	ThinCount        string `json:"thin_count"`
+	Discards         string `json:"discards"`
+	CacheMode        string `json:"cache_mode"`
+	Zero             string `json:"zero"`
+	TransactionID    string `json:"transaction_id"`
+	ThinID           string `json:"thin_id"`
+	Start            int64  `json:"seg_start,string"`
+	StartExtent      int64  `json:"seg_start_pe,string"`
+	Size             int64  `json:"seg_size,string"`
+	SizeExtents      int64  `json:"seg_size_pe,string"`
+	Tags             string `json:"seg_tags"`
+	PERanges         string `json:"seg_pe_ranges"`
+	LERanges         string `json:"seg_le_ranges"`
+	MetadataLERanges string `json:"seg_metadata_le_ranges"`
+	Devices          string `json:"devices"`
+	MetadataDevices  string `json:"metadata_devices"`
+	Monitor          string `json:"seg_monitor"`
+	CachePolicy      string `json:"cache_policy"`
+	CacheSettings    string `json:"cache_settings"`
+	LVUUID           string `json:"lv_uuid"`
+}
+
 // ReportEntry represents part of the information about local storage that is
 // produced by any of the "lvm vgs", "lvm pvs", or "lvm lvs" command.
 type ReportEntry struct {
@@ -230,9 +270,10 @@ type ReportEntry struct {
 // ReportEntryFull represents the information specific to a local volume group
 // that is produced by the "lvm fullreport" command.
 type ReportEntryFull struct {
-	PVs []ReportPVFull `json:"pv"`
-	VGs []ReportVGFull `json:"vg"`
-	LVs []ReportLVFull `json:"lv"`
+	PVs  []ReportPVFull  `json:"pv"`
+	VGs  []ReportVGFull  `json:"vg"`
+	LVs  []ReportLVFull  `json:"lv"`
+	Segs []ReportSegFull `json:"seg"`
 }
 
 // Report represents the information about local storage that is reported by
@@ -258,3 +299,16 @@ type LvmPoolHistory struct {
     PoolUUID string `json:"uuid"`
 }
 
+// MetadataArchive describes a copy of a volume group's metadata that was
+// produced by "lvm vgcfgbackup", either at our request or automatically by
+// lvm before it modified the volume group.  The sequence number corresponds
+// to the one reported in ReportVGFull.SequenceNumber.
+type MetadataArchive struct {
+	Path           string
+	VGName         string
+	VGUUID         string
+	SequenceNumber int64
+	Description    string
+	CreationHost   string
+	CreationTime   time.Time
+}
@@ -0,0 +1,125 @@
+package lvm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// UdevadmPath is the path to the "udevadm" command.
+	UdevadmPath string
+)
+
+func init() {
+	if p, err := exec.LookPath("udevadm"); err == nil {
+		UdevadmPath = p
+	}
+}
+
+// ActivateLogicalVolumeAndWait is a wrapper around DefaultClient.ActivateLogicalVolumeAndWait.
+func ActivateLogicalVolumeAndWait(vgname, volume string, options ActivationOptions, timeout time.Duration) (string, error) {
+	return DefaultClient.ActivateLogicalVolumeAndWait(vgname, volume, options, timeout)
+}
+
+// ActivateLogicalVolumeAndWait activates a single logical volume in the
+// specified volume group, and then waits up to the specified length of time
+// for udev to finish creating its device node, returning the node's path.  In
+// a dry run, the path is returned without waiting.  A volume which is flagged
+// to be skipped during activation is refused unless the options ignore the
+// flag, since lvm would quietly leave it inactive.
+func (c *Client) ActivateLogicalVolumeAndWait(vgname, volume string, options ActivationOptions, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	if !options.IgnoreActivationSkip {
+		report, err := c.GetLogicalVolumes(vgname, volume)
+		if err != nil {
+			return "", err
+		}
+		for _, entry := range report.Reports {
+			for _, lv := range entry.LVs {
+				// The tenth attribute is "k" if activation is skipped.
+				if lv.Name == volume && len(lv.Attributes) > 9 && lv.Attributes[9] == 'k' {
+					return "", errors.Errorf("LV %q is flagged to be skipped during activation, and the activation options don't ignore that", vgname+"/"+volume)
+				}
+			}
+		}
+	}
+	if err := c.ActivateLogicalVolumeWithOptions(vgname, volume, options); err != nil {
+		return "", err
+	}
+	path := filepath.Join("/dev/mapper", DeviceMapperName(vgname, volume))
+	if c.dryRun() {
+		return path, nil
+	}
+	if UdevadmPath != "" {
+		seconds := int(time.Until(deadline) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		if _, err := c.run(Command{Path: UdevadmPath, Args: []string{"settle", "--timeout=" + strconv.Itoa(seconds)}}); err != nil {
+			logrus.Debugf("error waiting for udev to settle: %v", err)
+		}
+	}
+	if err := waitForDevice(path, time.Until(deadline)); err != nil {
+		return "", errors.Wrapf(err, "error waiting for LV %q", vgname+"/"+volume)
+	}
+	return path, nil
+}
+
+// deviceIsOpenable checks if a device node exists and can be opened.
+func deviceIsOpenable(path string) bool {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// waitForDevice waits until the specified device node can be opened, watching
+// its directory for new entries, and checking periodically in case a change
+// is missed.
+func waitForDevice(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err == nil {
+		defer unix.Close(fd)
+		if _, err = unix.InotifyAddWatch(fd, filepath.Dir(path), unix.IN_CREATE|unix.IN_ATTRIB|unix.IN_MOVED_TO); err != nil {
+			logrus.Debugf("error watching %q: %v", filepath.Dir(path), err)
+		}
+	} else {
+		logrus.Debugf("error initializing inotify: %v", err)
+		fd = -1
+	}
+	buf := make([]byte, 4096)
+	for {
+		if deviceIsOpenable(path) {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return errors.Errorf("timed out waiting for %q", path)
+		}
+		if remaining > 100*time.Millisecond {
+			remaining = 100 * time.Millisecond
+		}
+		if fd < 0 {
+			time.Sleep(remaining)
+			continue
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		if n, err := unix.Poll(fds, int(remaining/time.Millisecond)); err == nil && n > 0 {
+			for {
+				if n, err := unix.Read(fd, buf); err != nil || n <= 0 {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package lvm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lvm-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "device")
+
+	if err = waitForDevice(path, 50*time.Millisecond); err == nil {
+		t.Fatalf("expected to time out waiting for %q", path)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ioutil.WriteFile(path, nil, 0600)
+	}()
+	if err = waitForDevice(path, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestActivateLogicalVolumeAndWaitSkipped(t *testing.T) {
+	script, _ := fakeLVM(t, `{"report": [{"lv": [{"lv_name": "lv", "vg_name": "vg", "lv_attr": "Vwi---tz-k"}]}]}`)
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: script, Executor: recorder}
+
+	start := time.Now()
+	_, err := c.ActivateLogicalVolumeAndWait("vg", "lv", ActivationOptions{}, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "skipped during activation") {
+		t.Fatalf("expected an error activating a skipped volume, got %v", err)
+	}
+	if time.Since(start) > 30*time.Second {
+		t.Fatalf("expected the error without waiting")
+	}
+	for _, cmd := range recorder.Commands() {
+		if cmd.Mutating {
+			t.Fatalf("expected the volume to not be activated, got %v", cmd)
+		}
+	}
+
+	path, err := c.ActivateLogicalVolumeAndWait("vg", "lv", ActivationOptions{IgnoreActivationSkip: true}, time.Minute)
+	if err != nil || path != "/dev/mapper/vg-lv" {
+		t.Fatalf("expected \"/dev/mapper/vg-lv\", got %q (%v)", path, err)
+	}
+}
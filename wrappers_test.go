@@ -2,6 +2,7 @@ package lvm
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -105,3 +106,27 @@ func TestGetVolumeGroupsFull(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestVolumeGroupTransferCommands(t *testing.T) {
+	recorder := &RecordingExecutor{}
+	c := &Client{LVMPath: "lvm", Executor: recorder}
+	for _, test := range []struct {
+		run      func() error
+		expected []string
+	}{
+		{func() error { return c.ExportVolumeGroup("vg") }, []string{"vgexport", "vg"}},
+		{func() error { return c.ImportVolumeGroup("vg") }, []string{"vgimport", "vg"}},
+		{func() error { return c.ImportClonedVolumeGroup("copy", "/dev/sdb", "/dev/sdc") }, []string{"vgimportclone", "--basevgname", "copy", "--import", "/dev/sdb", "/dev/sdc"}},
+		{func() error { return c.SetVolumeGroupSystemID("vg", "host1") }, []string{"vgchange", "--systemid", "host1", "vg"}},
+		{func() error { return c.SetVolumeGroupSystemID("vg", "") }, []string{"vgchange", "--systemid", "", "vg"}},
+	} {
+		recorder.Reset()
+		if err := test.run(); err != nil {
+			t.Fatal(err)
+		}
+		commands := recorder.Commands()
+		if len(commands) != 1 || !commands[0].Mutating || !reflect.DeepEqual(commands[0].Args, test.expected) {
+			t.Errorf("expected %q, got %v", test.expected, commands)
+		}
+	}
+}